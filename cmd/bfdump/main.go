@@ -0,0 +1,85 @@
+// 布隆过滤器快照工具
+//
+// 导出: bfdump -host localhost:6379 -keys seeds,links -dir ./dump export
+// 导入: bfdump -host localhost:6380 -keys seeds,links -dir ./dump import
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aivencs/box/pkg/filter"
+)
+
+func main() {
+	host := flag.String("host", "localhost:6379", "服务地址")
+	password := flag.String("password", "", "密码，填写时启用鉴权")
+	db := flag.Int("db", 0, "数据库")
+	keys := flag.String("keys", "", "过滤器键名，多个以逗号分隔")
+	dir := flag.String("dir", ".", "快照目录，文件名为键名加.bf后缀")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] export|import\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || len(*keys) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx := context.WithValue(context.Background(), "trace", "bfdump")
+	f, err := filter.NewBloomFilter(ctx, filter.Option{
+		Host:     *host,
+		Auth:     len(*password) > 0,
+		Password: *password,
+		DB:       *db,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	bf := f.(*filter.BloomFilter)
+	for _, key := range strings.Split(*keys, ",") {
+		key = strings.TrimSpace(key)
+		path := filepath.Join(*dir, key+".bf")
+		switch flag.Arg(0) {
+		case "export":
+			err = export(ctx, bf, key, path)
+		case "import":
+			err = restore(ctx, bf, key, path)
+		default:
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err != nil {
+			log.Fatalf("%s: %v", key, err)
+		}
+		log.Printf("%s: %s 完成", key, flag.Arg(0))
+	}
+}
+
+func export(ctx context.Context, bf *filter.BloomFilter, key string, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bf.ExportKey(ctx, key, file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func restore(ctx context.Context, bf *filter.BloomFilter, key string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = bf.ImportKey(ctx, key, file)
+	return err
+}
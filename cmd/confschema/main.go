@@ -0,0 +1,114 @@
+// 配置说明生成与检查工具
+//
+// 生成JSON Schema: confschema json box > box.schema.json
+// 生成Markdown:    confschema markdown > CONFIG.md
+// 检查配置文档:    confschema lint box app.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aivencs/box/pkg/box"
+	"github.com/aivencs/box/pkg/cache"
+	"github.com/aivencs/box/pkg/config"
+	"github.com/aivencs/box/pkg/filter"
+	featureflag "github.com/aivencs/box/pkg/flag"
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/aivencs/box/pkg/messenger"
+	"github.com/aivencs/box/pkg/request"
+	"github.com/aivencs/box/pkg/schema"
+	"github.com/aivencs/box/pkg/server"
+)
+
+// 支持的结构体，box为完整的组件配置文档
+var targets = map[string]interface{}{
+	"box":       box.Conf{},
+	"config":    config.Option{},
+	"logger":    logger.Option{},
+	"cache":     cache.Option{},
+	"filter":    filter.Option{},
+	"messenger": messenger.Option{},
+	"request":   request.Option{},
+	"server":    server.Option{},
+	"flag":      featureflag.Option{},
+}
+
+func main() {
+	format := flag.String("format", "", "lint时的文档格式，默认按扩展名判断")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] json|markdown|lint [目标] [文件]\n目标: %s\n", os.Args[0], strings.Join(names(), " "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch flag.Arg(0) {
+	case "json":
+		content, err := schema.JSONSchema(target(flag.Arg(1)), flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(content))
+	case "markdown":
+		list := names()
+		if flag.NArg() > 1 {
+			list = []string{flag.Arg(1)}
+		}
+		for _, name := range list {
+			fmt.Println(schema.Markdown(target(name), name))
+		}
+	case "lint":
+		if flag.NArg() < 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		path := flag.Arg(2)
+		doc, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+		problems, err := schema.Lint(doc, *format, target(flag.Arg(1)))
+		if err != nil {
+			log.Fatal(err)
+		}
+		failed := false
+		for _, problem := range problems {
+			fmt.Println(problem)
+			failed = failed || problem.Level == logger.ERROR
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func target(name string) interface{} {
+	v, ok := targets[name]
+	if !ok {
+		log.Fatalf("未知的目标%q，可选: %s", name, strings.Join(names(), " "))
+	}
+	return v
+}
+
+func names() []string {
+	list := make([]string, 0, len(targets))
+	for name := range targets {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,96 @@
+// 配置加密工具
+//
+// 生成密钥: confsecret genkey
+// 加密:     BOX_CONFIG_KEY=... confsecret encrypt "password"
+// 解密:     BOX_CONFIG_KEY=... confsecret decrypt "ENC(...)"
+// 轮换密钥: confsecret -old OLD_KEY -new NEW_KEY rotate < app.yaml > app.new.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aivencs/box/pkg/config"
+)
+
+func main() {
+	env := flag.String("env", config.DEFAULT_SECRET_ENV, "密钥环境变量")
+	file := flag.String("keyfile", "", "密钥文件路径，填写时优先于环境变量")
+	old := flag.String("old", "", "轮换时使用的旧密钥，多个以逗号分隔")
+	next := flag.String("new", "", "轮换时使用的新密钥")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] genkey|encrypt|decrypt|rotate [值]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	option := config.SecretOption{Env: *env, File: *file}
+	switch flag.Arg(0) {
+	case "genkey":
+		key, err := config.GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(key)
+	case "encrypt":
+		keys, err := config.LoadKeys(option)
+		if err != nil {
+			log.Fatal(err)
+		}
+		res, err := config.Encrypt(keys[0], value())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(res)
+	case "decrypt":
+		keys, err := config.LoadKeys(option)
+		if err != nil {
+			log.Fatal(err)
+		}
+		res, err := config.Decrypt(keys, value())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(res)
+	case "rotate":
+		oldKeys, err := config.ParseKeys(strings.Split(*old, ",")...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		newKeys, err := config.ParseKeys(*next)
+		if err != nil {
+			log.Fatal(err)
+		}
+		document, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		res, err := config.Rotate(string(document), oldKeys, newKeys[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(res)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// 待处理的值，未通过参数提供时从标准输入读取
+func value() string {
+	if flag.NArg() > 1 {
+		return flag.Arg(1)
+	}
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return strings.TrimRight(string(content), "\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aivencs/box/pkg/box"
+	"github.com/aivencs/box/pkg/config"
+	"github.com/aivencs/box/pkg/server"
+)
+
+// 业务配置，组件配置以squash嵌入，配置文档顶层即为logger、cache等组件
+//
+// logger:
+//
+//	enable: true
+//	application: demo
+//	env: dev
+//	label: api
+//
+// server:
+//
+//	enable: true
+//	port: 9817
+type BindConf struct {
+	box.Conf `json:",squash"`
+	Greeting string `json:"greeting"`
+}
+
+var Conf = BindConf{}
+
+func main() {
+	ctx := context.WithValue(context.Background(), "trace", "v001")
+	err := config.InitConf(ctx, config.FILE, config.Option{
+		Application: "demo",
+		Env:         "dev",
+		Host:        "app.yaml",
+		Type:        "yaml",
+		Bind:        &Conf,
+		Tag:         config.TAG_JSON,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 一次返回所有未通过校验的参数
+	if err := box.Bootstrap(ctx, &Conf.Conf); err != nil {
+		log.Fatal(err)
+	}
+	server.Work()
+}
@@ -75,9 +75,14 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// 监听变更
+	config.OnChange(func(old, new interface{}, diff []config.Change) {
+		fmt.Println("changed: ", diff)
+	})
 	// 使用方法
 	for i := 0; i < 1000; i++ {
-		fmt.Println("bind-", i, ": ", Conf) // 直接访问
+		fmt.Println("bind-", i, ": ", Conf)                            // 首次读取的值
+		fmt.Println("current-", i, ": ", config.Current().(*BindConf)) // 当前生效的值
 		// 期间可以修改配置中的内容，以观察自动定时更新是否生效
 		time.Sleep(time.Second * 3)
 	}
@@ -0,0 +1,131 @@
+// 按统一的配置文档初始化各组件
+package box
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aivencs/box/pkg/cache"
+	"github.com/aivencs/box/pkg/filter"
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/aivencs/box/pkg/messenger"
+	"github.com/aivencs/box/pkg/request"
+	"github.com/aivencs/box/pkg/server"
+	"github.com/aivencs/box/pkg/validate"
+)
+
+// 组件配置
+// 可直接作为config的Bind，或以 json:",squash" 嵌入业务配置结构体
+// 作为Bind时需将config.Option.Tag设为json
+// 各组件参数在Bootstrap时仅对已启用的组件校验
+type Conf struct {
+	Logger    LoggerSection    `json:"logger" label:"日志"`
+	Cache     CacheSection     `json:"cache" label:"缓存"`
+	Filter    FilterSection    `json:"filter" label:"过滤器"`
+	Messenger MessengerSection `json:"messenger" label:"消息队列"`
+	Request   RequestSection   `json:"request" label:"请求"`
+	Server    ServerSection    `json:"server" label:"服务"`
+}
+
+type LoggerSection struct {
+	Enable        bool               `json:"enable" label:"是否启用"`
+	Type          logger.TypeSupport `json:"type" label:"类型" desc:"默认为zap"`
+	logger.Option `json:",squash" validate:"-"`
+}
+
+type CacheSection struct {
+	Enable       bool              `json:"enable" label:"是否启用"`
+	Type         cache.TypeSupport `json:"type" label:"类型" desc:"默认为redis"`
+	cache.Option `json:",squash" validate:"-"`
+}
+
+type FilterSection struct {
+	Enable        bool               `json:"enable" label:"是否启用"`
+	Type          filter.TypeSupport `json:"type" label:"类型" desc:"默认为bloom"`
+	filter.Option `json:",squash" validate:"-"`
+}
+
+type MessengerSection struct {
+	Enable           bool                  `json:"enable" label:"是否启用"`
+	Type             messenger.TypeSupport `json:"type" label:"类型" desc:"默认为rabbitmq"`
+	messenger.Option `json:",squash" validate:"-"`
+}
+
+type RequestSection struct {
+	Enable         bool                `json:"enable" label:"是否启用"`
+	Type           request.TypeSupport `json:"type" label:"类型" desc:"默认为resty"`
+	request.Option `json:",squash" validate:"-"`
+}
+
+type ServerSection struct {
+	Enable        bool               `json:"enable" label:"是否启用"`
+	Type          server.TypeSupport `json:"type" label:"类型" desc:"默认为echo"`
+	server.Option `json:",squash" validate:"-"`
+}
+
+// 待初始化的组件
+type component struct {
+	name   string
+	enable bool
+	option interface{}
+	init   func(ctx context.Context) error
+}
+
+// 按依赖顺序排列，日志最先初始化，服务最后初始化
+func components(conf *Conf) []component {
+	return []component{
+		{"logger", conf.Logger.Enable, &conf.Logger.Option, func(ctx context.Context) error {
+			return logger.InitLogger(ctx, conf.Logger.Type, conf.Logger.Option)
+		}},
+		{"cache", conf.Cache.Enable, &conf.Cache.Option, func(ctx context.Context) error {
+			return cache.InitCache(ctx, conf.Cache.Type, conf.Cache.Option)
+		}},
+		{"filter", conf.Filter.Enable, &conf.Filter.Option, func(ctx context.Context) error {
+			return filter.InitFilter(ctx, conf.Filter.Type, conf.Filter.Option)
+		}},
+		{"messenger", conf.Messenger.Enable, &conf.Messenger.Option, func(ctx context.Context) error {
+			return messenger.InitMessenger(ctx, conf.Messenger.Type, conf.Messenger.Option)
+		}},
+		{"request", conf.Request.Enable, &conf.Request.Option, func(ctx context.Context) error {
+			return request.InitRequest(ctx, conf.Request.Type, conf.Request.Option)
+		}},
+		{"server", conf.Server.Enable, &conf.Server.Option, func(ctx context.Context) error {
+			return server.InitServer(ctx, conf.Server.Type, conf.Server.Option)
+		}},
+	}
+}
+
+// 初始化已启用的组件
+// 先校验所有已启用组件的参数并一次返回全部问题，校验通过后再依次初始化
+func Bootstrap(ctx context.Context, conf *Conf) error {
+	if conf == nil {
+		return logger.NewError(logger.PVERROR, "配置不可为空", nil)
+	}
+	list := components(conf)
+	problems := []string{}
+	for _, item := range list {
+		if !item.enable {
+			continue
+		}
+		messages, err := validate.Messages(ctx, item.option)
+		if err == nil {
+			continue
+		}
+		for _, message := range messages {
+			problems = append(problems, fmt.Sprintf("%s: %s", item.name, message))
+		}
+	}
+	if len(problems) > 0 {
+		return logger.NewError(logger.PVERROR, strings.Join(problems, "; "), nil)
+	}
+	for _, item := range list {
+		if !item.enable {
+			continue
+		}
+		if err := item.init(ctx); err != nil {
+			return logger.NewError(logger.CALLERROR, fmt.Sprintf("%s初始化失败: %v", item.name, err), err)
+		}
+	}
+	return nil
+}
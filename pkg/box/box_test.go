@@ -0,0 +1,106 @@
+package box
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aivencs/box/pkg/config"
+	"github.com/aivencs/box/pkg/filter"
+	"github.com/aivencs/box/pkg/logger"
+)
+
+// 读取YAML配置文档并映射到Conf
+func load(t *testing.T, document string) *Conf {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte(document), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf := &Conf{}
+	option := config.Option{Host: path, Application: "app", Env: "test", Type: "yaml", Bind: conf, Tag: config.TAG_JSON}
+	if _, err := config.NewFileConf(context.Background(), option); err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+// 未启用的组件参数缺失或有误时不影响校验
+func TestBootstrapValidatesEnabledOnly(t *testing.T) {
+	conf := load(t, `
+cache:
+  enable: true
+server:
+  enable: true
+  port: 1
+messenger:
+  enable: false
+  port: 1
+`)
+	err := Bootstrap(context.Background(), conf)
+	if err == nil {
+		t.Fatal("Bootstrap with invalid sections succeeded")
+	}
+	message := err.Error()
+	for _, want := range []string{"cache: ", "server: "} {
+		if !strings.Contains(message, want) {
+			t.Errorf("error %q does not mention %q", message, want)
+		}
+	}
+	if strings.Contains(message, "messenger") {
+		t.Errorf("error %q mentions the disabled messenger", message)
+	}
+	if logger.Initialized() {
+		t.Error("logger initialized although validation failed")
+	}
+}
+
+// 仅初始化已启用的组件，嵌入的组件参数按json标签展开
+func TestBootstrapPartial(t *testing.T) {
+	conf := load(t, `
+logger:
+  enable: true
+  application: app
+  env: test
+  label: box
+  level: warn
+filter:
+  enable: true
+  type: set
+  host: 127.0.0.1:6379
+  key: seen
+  buckets: 4
+request:
+  enable: true
+cache:
+  enable: false
+messenger:
+  enable: false
+server:
+  enable: false
+  port: 1
+`)
+	if conf.Logger.Application != "app" || conf.Logger.Level != logger.WARN {
+		t.Fatalf("logger section = %+v", conf.Logger)
+	}
+	if conf.Filter.Type != filter.SET || conf.Filter.Host != "127.0.0.1:6379" || conf.Filter.Buckets != 4 {
+		t.Fatalf("filter section = %+v", conf.Filter)
+	}
+	if conf.Server.Enable || conf.Server.Port != 1 {
+		t.Fatalf("server section = %+v", conf.Server)
+	}
+	if err := Bootstrap(context.Background(), conf); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.Initialized() {
+		t.Error("logger not initialized")
+	}
+}
+
+func TestBootstrapNil(t *testing.T) {
+	if err := Bootstrap(context.Background(), nil); err == nil {
+		t.Error("Bootstrap(nil) succeeded")
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/spf13/cast"
+)
+
+// 按键名读取配置
+// 键名以点号分隔层级，未设置时返回默认值，无默认值时返回错误
+type Reader interface {
+	GetString(key string, def ...string) (string, error)
+	GetInt(key string, def ...int) (int, error)
+	GetBool(key string, def ...bool) (bool, error)
+	GetDuration(key string, def ...time.Duration) (time.Duration, error)
+	GetStringSlice(key string, def ...[]string) ([]string, error)
+	Sub(path string) Reader
+	IsSet(key string) bool
+	UnmarshalKey(key string, target interface{}) error
+}
+
+// 配置的局部视图
+// 每次读取时取当前生效的配置，更新后无需重新获取
+type section struct {
+	store  *store
+	prefix string
+}
+
+func (c *store) GetString(key string, def ...string) (string, error) {
+	return c.Sub("").GetString(key, def...)
+}
+
+func (c *store) GetInt(key string, def ...int) (int, error) {
+	return c.Sub("").GetInt(key, def...)
+}
+
+func (c *store) GetBool(key string, def ...bool) (bool, error) {
+	return c.Sub("").GetBool(key, def...)
+}
+
+func (c *store) GetDuration(key string, def ...time.Duration) (time.Duration, error) {
+	return c.Sub("").GetDuration(key, def...)
+}
+
+func (c *store) GetStringSlice(key string, def ...[]string) ([]string, error) {
+	return c.Sub("").GetStringSlice(key, def...)
+}
+
+func (c *store) IsSet(key string) bool {
+	return c.Sub("").IsSet(key)
+}
+
+func (c *store) UnmarshalKey(key string, target interface{}) error {
+	return c.Sub("").UnmarshalKey(key, target)
+}
+
+func (c *store) Sub(path string) Reader {
+	return &section{store: c, prefix: strings.ToLower(path)}
+}
+
+func (c *section) Sub(path string) Reader {
+	return &section{store: c.store, prefix: c.key(path)}
+}
+
+func (c *section) IsSet(key string) bool {
+	_, ok := c.value(key)
+	return ok
+}
+
+// 将配置项映射到结构体，标签与Option.Tag一致
+func (c *section) UnmarshalKey(key string, target interface{}) error {
+	if err := c.ready(); err != nil {
+		return err
+	}
+	s := c.store.load()
+	if s == nil || !s.kernel.IsSet(c.key(key)) {
+		return c.missing(key)
+	}
+	if err := s.kernel.UnmarshalKey(c.key(key), target, withTag(s.tag)); err != nil {
+		return logger.NewError(logger.EDERROR, fmt.Sprintf("配置项%s映射失败", c.key(key)), err)
+	}
+	return nil
+}
+
+func (c *section) GetString(key string, def ...string) (string, error) {
+	if err := c.ready(); err != nil {
+		return "", err
+	}
+	val, ok := c.value(key)
+	if !ok {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return "", c.missing(key)
+	}
+	res, err := cast.ToStringE(val)
+	if err != nil {
+		return "", c.invalid(key, "string", err)
+	}
+	return res, nil
+}
+
+func (c *section) GetInt(key string, def ...int) (int, error) {
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	val, ok := c.value(key)
+	if !ok {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return 0, c.missing(key)
+	}
+	res, err := cast.ToIntE(val)
+	if err != nil {
+		return 0, c.invalid(key, "int", err)
+	}
+	return res, nil
+}
+
+func (c *section) GetBool(key string, def ...bool) (bool, error) {
+	if err := c.ready(); err != nil {
+		return false, err
+	}
+	val, ok := c.value(key)
+	if !ok {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return false, c.missing(key)
+	}
+	res, err := cast.ToBoolE(val)
+	if err != nil {
+		return false, c.invalid(key, "bool", err)
+	}
+	return res, nil
+}
+
+func (c *section) GetDuration(key string, def ...time.Duration) (time.Duration, error) {
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	val, ok := c.value(key)
+	if !ok {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return 0, c.missing(key)
+	}
+	res, err := cast.ToDurationE(val)
+	if err != nil {
+		return 0, c.invalid(key, "duration", err)
+	}
+	return res, nil
+}
+
+func (c *section) GetStringSlice(key string, def ...[]string) ([]string, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	val, ok := c.value(key)
+	if !ok {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return nil, c.missing(key)
+	}
+	res, err := cast.ToStringSliceE(val)
+	if err != nil {
+		return nil, c.invalid(key, "[]string", err)
+	}
+	return res, nil
+}
+
+func (c *section) key(key string) string {
+	key = strings.ToLower(key)
+	if c.prefix == "" {
+		return key
+	}
+	if key == "" {
+		return c.prefix
+	}
+	return c.prefix + "." + key
+}
+
+// 配置未初始化时返回错误
+func (c *section) ready() error {
+	if c.store == nil {
+		return logger.NewError(logger.RPERROR, "配置未初始化", nil)
+	}
+	return nil
+}
+
+func (c *section) value(key string) (interface{}, bool) {
+	if c.store == nil {
+		return nil, false
+	}
+	s := c.store.load()
+	if s == nil {
+		return nil, false
+	}
+	key = c.key(key)
+	if !s.kernel.IsSet(key) {
+		return nil, false
+	}
+	return s.kernel.Get(key), true
+}
+
+func (c *section) missing(key string) error {
+	return logger.NewError(logger.CHECK, fmt.Sprintf("配置项%s不存在", c.key(key)), nil)
+}
+
+func (c *section) invalid(key string, kind string, err error) error {
+	return logger.NewError(logger.EDERROR, fmt.Sprintf("配置项%s无法转换为%s", c.key(key), kind), err)
+}
+
+// 包级别读取所用的对象，未初始化时各方法返回错误
+func reader() Reader {
+	if conf == nil {
+		return &section{}
+	}
+	return conf
+}
+
+func GetString(key string, def ...string) (string, error) {
+	return reader().GetString(key, def...)
+}
+
+func GetInt(key string, def ...int) (int, error) {
+	return reader().GetInt(key, def...)
+}
+
+func GetBool(key string, def ...bool) (bool, error) {
+	return reader().GetBool(key, def...)
+}
+
+func GetDuration(key string, def ...time.Duration) (time.Duration, error) {
+	return reader().GetDuration(key, def...)
+}
+
+func GetStringSlice(key string, def ...[]string) ([]string, error) {
+	return reader().GetStringSlice(key, def...)
+}
+
+func Sub(path string) Reader {
+	return reader().Sub(path)
+}
+
+func IsSet(key string) bool {
+	return reader().IsSet(key)
+}
+
+func UnmarshalKey(key string, target interface{}) error {
+	return reader().UnmarshalKey(key, target)
+}
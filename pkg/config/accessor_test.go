@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+// 初始化前调用包级别函数应返回错误而非panic
+func TestUninitialized(t *testing.T) {
+	if conf != nil {
+		t.Skip("config already initialized")
+	}
+	errs := map[string]error{}
+	_, errs["GetString"] = GetString("a", "def")
+	_, errs["GetInt"] = GetInt("a")
+	_, errs["GetBool"] = GetBool("a")
+	_, errs["GetDuration"] = GetDuration("a")
+	_, errs["GetStringSlice"] = GetStringSlice("a")
+	_, errs["Sub.GetString"] = Sub("section").GetString("a")
+	errs["UnmarshalKey"] = UnmarshalKey("a", &struct{}{})
+	errs["OnChange"] = OnChange(func(old, new interface{}, diff []Change) {})
+	for name, err := range errs {
+		ers, ok := err.(*logger.BaseError)
+		if !ok || ers.Code() != logger.RPERROR {
+			t.Errorf("%s: err = %v, want RPERROR", name, err)
+		}
+	}
+	if IsSet("a") || Current() != nil || SourceRevision() != "" || Audits() != nil || Stale() {
+		t.Error("uninitialized config should report zero values")
+	}
+}
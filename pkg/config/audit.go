@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+const (
+	DEFAULT_AUDIT_SIZE = 100      // 默认保留的变更记录数量
+	DEFAULT_MASK       = "******" // 敏感值的替代显示
+)
+
+// 键名包含以下内容时视为敏感值
+var sensitiveWords = []string{"password", "passwd", "secret", "token", "credential", "private"}
+
+// 配置变更记录
+// 敏感值已替换为******
+type Audit struct {
+	Timestamp time.Time `json:"timestamp" label:"生效时间"`
+	Revision  string    `json:"revision" label:"来源版本" desc:"Consul为索引，Etcd为版本号，文件为修改时间"`
+	Changes   []Change  `json:"changes" label:"变更项"`
+}
+
+// 记录即将应用的配置来源版本
+func (c *store) track(revision string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = revision
+}
+
+// 当前生效配置的来源版本
+func (c *store) SourceRevision() string {
+	if s := c.load(); s != nil {
+		return s.revision
+	}
+	return ""
+}
+
+// 变更记录，按时间升序
+func (c *store) Audits() []Audit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Audit(nil), c.audits...)
+}
+
+// 记录变更并输出日志，调用方需持有锁
+// 敏感键取本次与上次配置的并集，以覆盖删除或改为明文的加密值
+func (c *store) audit(ctx context.Context, revision string, diff []Change, secrets map[string]bool) {
+	union := map[string]bool{}
+	for _, keys := range []map[string]bool{c.secrets, secrets} {
+		for key := range keys {
+			union[key] = true
+		}
+	}
+	record := Audit{Timestamp: time.Now(), Revision: revision, Changes: mask(diff, union)}
+	c.audits = append(c.audits, record)
+	if len(c.audits) > DEFAULT_AUDIT_SIZE {
+		c.audits = c.audits[len(c.audits)-DEFAULT_AUDIT_SIZE:]
+	}
+	if !logger.Initialized() {
+		log.Printf("配置已变更: 版本%s %v", revision, record.Changes)
+		return
+	}
+	if _, ok := ctx.Value("trace").(string); !ok {
+		ctx = context.WithValue(ctx, "trace", "config-update")
+	}
+	logger.Info(ctx, logger.Message{
+		Text: "配置已变更",
+		Attr: logger.Attr{
+			Monitor: logger.Monitor{
+				Code:  logger.CONFCHANGE,
+				Level: logger.GetLevelBaseCode(logger.CONFCHANGE),
+			},
+			Oup: map[string]interface{}{
+				"revision": revision,
+				"changes":  record.Changes,
+			},
+		},
+	})
+}
+
+// 原始配置中值为ENC(base64)或引用的键
+// 引用的环境变量与文件常用于传递密钥，展开后的值同样视为敏感
+func secretKeys(settings map[string]interface{}) map[string]bool {
+	secrets := map[string]bool{}
+	for key, val := range flatten("", settings) {
+		if hasSecret(val) || hasReference(val) {
+			secrets[key] = true
+		}
+	}
+	return secrets
+}
+
+// 替换敏感值，键名包含敏感词的键同样视为敏感
+func mask(diff []Change, secrets map[string]bool) []Change {
+	result := make([]Change, len(diff))
+	for i, change := range diff {
+		if secrets[change.Key] || sensitive(change.Key) {
+			if change.Old != nil {
+				change.Old = DEFAULT_MASK
+			}
+			if change.New != nil {
+				change.New = DEFAULT_MASK
+			}
+		}
+		result[i] = change
+	}
+	return result
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// 变更记录
+func Audits() []Audit {
+	if conf == nil {
+		return nil
+	}
+	return conf.Audits()
+}
+
+// 当前生效配置的来源版本
+func SourceRevision() string {
+	if conf == nil {
+		return ""
+	}
+	return conf.SourceRevision()
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 值为引用或键名含敏感词时，变更记录中不显示展开后的值
+func TestAuditMasksReferences(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dsn")
+	if err := os.WriteFile(path, []byte("mysql://root:v1@db\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BOX_TEST_TOKEN", "v1")
+	settings := map[string]interface{}{
+		"dsn":     "@file:" + path,
+		"api":     "${BOX_TEST_TOKEN}",
+		"secret":  "s1",
+		"name":    "v1",
+		"literal": "$${name}",
+	}
+	c := &store{}
+	if _, err := c.apply(ctx, settings, Option{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("mysql://root:v2@db\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BOX_TEST_TOKEN", "v2")
+	settings["secret"] = "s2"
+	settings["name"] = "v2"
+	if _, err := c.apply(ctx, settings, Option{}); err != nil {
+		t.Fatal(err)
+	}
+	audits := c.Audits()
+	if len(audits) != 1 {
+		t.Fatalf("audits = %v", audits)
+	}
+	changes := map[string]Change{}
+	for _, change := range audits[0].Changes {
+		changes[change.Key] = change
+	}
+	for _, key := range []string{"dsn", "api", "secret"} {
+		if change := changes[key]; change.Old != DEFAULT_MASK || change.New != DEFAULT_MASK {
+			t.Errorf("%s = %+v, want masked", key, change)
+		}
+	}
+	if change := changes["name"]; change.Old != "v1" || change.New != "v2" {
+		t.Errorf("name = %+v, want plain", change)
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 本地快照
+// 保存解密前的原始配置，加密值不会以明文落盘
+type Backup struct {
+	Application string                 `json:"application" label:"应用名称"`
+	Env         string                 `json:"env" label:"环境"`
+	Timestamp   time.Time              `json:"timestamp" label:"保存时间"`
+	Checksum    string                 `json:"checksum" label:"校验值" desc:"settings的sha256"`
+	Settings    map[string]interface{} `json:"-" label:"配置"`
+}
+
+// 快照文件的存储格式
+type backupFile struct {
+	Backup
+	Raw json.RawMessage `json:"settings"`
+}
+
+// 写入本地快照
+// 先写临时文件再重命名，避免进程中断时留下不完整的快照
+func SaveBackup(option Option, settings map[string]interface{}) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return logger.NewError(logger.EDERROR, "配置快照编码失败", err)
+	}
+	sum := sha256.Sum256(raw)
+	content, err := json.Marshal(backupFile{
+		Backup: Backup{
+			Application: option.Application,
+			Env:         option.Env,
+			Timestamp:   time.Now(),
+			Checksum:    hex.EncodeToString(sum[:]),
+		},
+		Raw: raw,
+	})
+	if err != nil {
+		return logger.NewError(logger.EDERROR, "配置快照编码失败", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(option.Snapshot), 0755); err != nil {
+		return logger.NewError(logger.RWARN, "创建快照目录失败", err)
+	}
+	tmp := option.Snapshot + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return logger.NewError(logger.RWARN, "写入配置快照失败", err)
+	}
+	if err := os.Rename(tmp, option.Snapshot); err != nil {
+		return logger.NewError(logger.RWARN, "写入配置快照失败", err)
+	}
+	return nil
+}
+
+// 读取本地快照
+// 校验值不符或应用、环境不一致时返回错误
+func LoadBackup(option Option) (*Backup, error) {
+	content, err := os.ReadFile(option.Snapshot)
+	if err != nil {
+		return nil, logger.NewError(logger.PVERROR, "读取配置快照失败", err)
+	}
+	var file backupFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置快照解析失败", err)
+	}
+	sum := sha256.Sum256(file.Raw)
+	if hex.EncodeToString(sum[:]) != file.Checksum {
+		return nil, logger.NewError(logger.CHECK, "配置快照校验失败", nil)
+	}
+	if file.Application != option.Application || file.Env != option.Env {
+		return nil, logger.NewError(logger.CHECK, "配置快照与应用或环境不符", nil)
+	}
+	if err := json.Unmarshal(file.Raw, &file.Settings); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置快照解析失败", err)
+	}
+	return &file.Backup, nil
+}
+
+// 是否为连接远端失败，仅网络错误或超时时使用本地快照
+// 鉴权失败、无权限等服务端的明确响应不使用快照，避免掩盖配置错误
+func unreachable(err error) bool {
+	ers, ok := err.(*logger.BaseError)
+	if !ok || ers.Code() != logger.CALLERROR {
+		return false
+	}
+	cause := ers.UnWrap()
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(cause, &netErr) {
+		return true
+	}
+	// Etcd以gRPC状态码区分服务不可用与请求被拒绝
+	code := codes.Unknown
+	if e, ok := cause.(rpctypes.EtcdError); ok {
+		code = e.Code()
+	} else if s, ok := status.FromError(cause); ok {
+		code = s.Code()
+	}
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/hashicorp/consul/api"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnreachable(t *testing.T) {
+	refused := &url.Error{Op: "Get", URL: "http://127.0.0.1:8500", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection refused", err: refused, want: true},
+		{name: "timeout", err: context.DeadlineExceeded, want: true},
+		{name: "grpc unavailable", err: status.Error(codes.Unavailable, "connection refused"), want: true},
+		{name: "grpc deadline", err: status.Error(codes.DeadlineExceeded, "deadline"), want: true},
+		{name: "consul acl denied", err: api.StatusError{Code: http.StatusForbidden, Body: "ACL not found"}},
+		{name: "consul unauthorized", err: api.StatusError{Code: http.StatusUnauthorized}},
+		{name: "consul not found", err: api.StatusError{Code: http.StatusNotFound}},
+		{name: "etcd permission denied", err: rpctypes.ErrPermissionDenied},
+		{name: "etcd auth failed", err: rpctypes.ErrAuthFailed},
+		{name: "other", err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		if got := unreachable(logger.NewError(logger.CALLERROR, "读取失败", tt.err)); got != tt.want {
+			t.Errorf("%s: unreachable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// 非连接类错误码不使用快照
+	if unreachable(logger.NewError(logger.EDERROR, "配置解析失败", refused)) {
+		t.Error("unreachable(EDERROR) = true")
+	}
+	if unreachable(refused) {
+		t.Error("unreachable(unwrapped) = true")
+	}
+}
+
+// 无法连接时使用快照，服务端拒绝时直接报错
+func TestConsulSnapshotFallback(t *testing.T) {
+	ctx := context.Background()
+	option := Option{Application: "app", Env: "dev", Type: "yaml", Snapshot: filepath.Join(t.TempDir(), "app.json")}
+	if err := SaveBackup(option, map[string]interface{}{"name": "snapshot"}); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeConsul{stop: make(chan struct{}), replies: []consulReply{{status: http.StatusForbidden}}}
+	srv := httptest.NewServer(f)
+	defer close(f.stop)
+	option.Host = srv.URL
+	option.Bind = &nameConf{}
+	if _, err := NewConsulConf(ctx, option); err == nil {
+		t.Fatal("NewConsulConf with ACL denied used the snapshot")
+	}
+	srv.Close()
+	option.Bind = &nameConf{}
+	c, err := NewConsulConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Stale() || c.Current().(*nameConf).Name != "snapshot" {
+		t.Errorf("Stale = %v, name = %q, want snapshot", c.Stale(), c.Current().(*nameConf).Name)
+	}
+}
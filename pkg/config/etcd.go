@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/spf13/viper"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	DEFAULT_ETCD_DIAL_TIMEOUT = 5 * time.Second // Etcd 默认连接超时时间
+)
+
+// Conf 结构体
+// 基于 Etcd v3
+type EtcdConf struct {
+	store
+	Kernel   *viper.Viper
+	Client   *clientv3.Client
+	Key      string
+	Revision int64
+}
+
+// 创建基于Etcd的配置对象
+// Host 可填写多个地址，以逗号分隔
+func NewEtcdConf(ctx context.Context, option Option) (Conf, error) {
+	if utf8.RuneCountInString(option.Host) == 0 {
+		option.Host = DEFAULT_HOST_ETCD
+	}
+	cfg := clientv3.Config{
+		Endpoints:   strings.Split(option.Host, ","),
+		DialTimeout: DEFAULT_ETCD_DIAL_TIMEOUT,
+	}
+	if option.Auth {
+		cfg.Username = option.Username
+		cfg.Password = option.Password
+	}
+	if utf8.RuneCountInString(option.CAFile) > 0 {
+		info := transport.TLSInfo{
+			TrustedCAFile: option.CAFile,
+			CertFile:      option.CertFile,
+			KeyFile:       option.KeyFile,
+		}
+		tlsConf, err := info.ClientConfig()
+		if err != nil {
+			return nil, logger.NewError(logger.PVERROR, "证书有误", err)
+		}
+		cfg.TLS = tlsConf
+	}
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewEtcdConfFromClient(ctx, client, option)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// 使用已有的客户端创建配置对象
+// 便于接入进程内启动的Etcd服务
+func NewEtcdConfFromClient(ctx context.Context, client *clientv3.Client, option Option) (Conf, error) {
+	vip := viper.New()
+	vip.SetConfigType(option.Type)
+	c := &EtcdConf{Kernel: vip, Client: client, Key: fmt.Sprintf("/%s/%s", option.Application, option.Env)}
+	// 解析配置并映射到结构体，无法连接时使用本地快照
+	settings, err := c.reload(ctx)
+	if err != nil {
+		if !unreachable(err) {
+			return nil, err
+		}
+		if err := c.fallback(ctx, c.Kernel, option, err); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if _, err := c.apply(ctx, settings, option); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 通过Etcd的监听接口即时更新
+// 从读取时的版本开始监听，避免遗漏期间的修改
+// 监听中断后从最后处理的版本重新监听，版本已被压缩时先全量读取
+func (c *EtcdConf) PeriodicUpdate(ctx context.Context, option Option) {
+	// 使用本地快照启动时，先重试读取以获得监听的起始版本
+	backoff := time.Duration(0)
+	for c.Stale() {
+		settings, err := c.reload(ctx)
+		if err == nil {
+			c.update(ctx, settings, option)
+			break
+		}
+		report(ctx, "读取配置失败", err)
+		backoff = next(backoff)
+		if !sleep(ctx, backoff) {
+			return
+		}
+	}
+	backoff = 0
+	for {
+		received, err := c.watch(ctx, option)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = 0
+		}
+		if err == rpctypes.ErrCompacted {
+			report(ctx, "监听版本已被压缩，重新读取配置", err)
+			settings, err := c.reload(ctx)
+			if err == nil {
+				c.update(ctx, settings, option)
+				continue
+			}
+			report(ctx, "读取配置失败", err)
+		} else {
+			report(ctx, "监听配置中断", err)
+		}
+		backoff = next(backoff)
+		if !sleep(ctx, backoff) {
+			return
+		}
+	}
+}
+
+// 监听一次，直至出错或通道关闭
+// 返回期间是否收到过事件
+func (c *EtcdConf) watch(ctx context.Context, option Option) (bool, error) {
+	// 要求有主节点，避免网络分区时无声地停止更新
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	received := false
+	for res := range c.Client.Watch(ctx, c.Key, clientv3.WithRev(c.Revision+1)) {
+		if err := res.Err(); err != nil {
+			return received, err
+		}
+		for _, event := range res.Events {
+			received = true
+			c.Revision = event.Kv.ModRevision
+			if event.Type != clientv3.EventTypePut {
+				continue
+			}
+			c.track(strconv.FormatInt(c.Revision, 10))
+			if err := c.Kernel.ReadConfig(bytes.NewReader(event.Kv.Value)); err != nil {
+				report(ctx, "读取配置失败", logger.NewError(logger.EDERROR, "配置解析失败", err))
+				continue
+			}
+			c.update(ctx, c.Kernel.AllSettings(), option)
+		}
+	}
+	return received, errors.New("监听通道已关闭")
+}
+
+// 重新读取配置
+// 限定超时，避免远端不可用时一直阻塞
+func (c *EtcdConf) reload(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_ETCD_DIAL_TIMEOUT)
+	defer cancel()
+	res, err := c.Client.Get(ctx, c.Key)
+	if err != nil {
+		return nil, logger.NewError(logger.CALLERROR, "读取Etcd失败", err)
+	}
+	if len(res.Kvs) == 0 {
+		return nil, logger.NewError(logger.CHECK, "配置不存在", nil)
+	}
+	if err := c.Kernel.ReadConfig(bytes.NewReader(res.Kvs[0].Value)); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置解析失败", err)
+	}
+	c.Revision = res.Header.Revision
+	c.track(strconv.FormatInt(c.Revision, 10))
+	return c.Kernel.AllSettings(), nil
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+type nameConf struct {
+	Name string `json:"name"`
+}
+
+func waitName(t *testing.T, c Conf, want string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if c.Current().(*nameConf).Name == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("name = %q, want %q", c.Current().(*nameConf).Name, want)
+}
+
+// 进程内的Etcd服务，重启时沿用数据目录与端口
+type testEtcd struct {
+	t      *testing.T
+	dir    string
+	client url.URL
+	peer   url.URL
+	setup  func(cfg *embed.Config)
+	server *embed.Etcd
+}
+
+func freeURL(t *testing.T, scheme string) url.URL {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return url.URL{Scheme: scheme, Host: lis.Addr().String()}
+}
+
+func startEtcd(t *testing.T, scheme string, setup func(cfg *embed.Config)) *testEtcd {
+	e := &testEtcd{
+		t:      t,
+		dir:    filepath.Join(t.TempDir(), "etcd"),
+		client: freeURL(t, scheme),
+		peer:   freeURL(t, "http"),
+		setup:  setup,
+	}
+	e.start()
+	t.Cleanup(e.stop)
+	return e
+}
+
+func (e *testEtcd) start() {
+	cfg := embed.NewConfig()
+	cfg.Dir = e.dir
+	cfg.LogLevel = "error"
+	cfg.LCUrls, cfg.ACUrls = []url.URL{e.client}, []url.URL{e.client}
+	cfg.LPUrls, cfg.APUrls = []url.URL{e.peer}, []url.URL{e.peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	if e.setup != nil {
+		e.setup(cfg)
+	}
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		server.Close()
+		e.t.Fatal("etcd not ready")
+	}
+	e.server = server
+}
+
+func (e *testEtcd) stop() {
+	if e.server != nil {
+		e.server.Close()
+		e.server = nil
+	}
+}
+
+func (e *testEtcd) endpoint() string {
+	return e.client.Host
+}
+
+// 用于写入数据的客户端
+func (e *testEtcd) dial(cfg clientv3.Config) *clientv3.Client {
+	cfg.Endpoints = []string{e.endpoint()}
+	cfg.DialTimeout = 5 * time.Second
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func put(t *testing.T, client *clientv3.Client, key string, value string) int64 {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := client.Put(ctx, key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Header.Revision
+}
+
+func TestEtcdWatchAfterCompaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := startEtcd(t, "http", nil)
+	writer := e.dial(clientv3.Config{})
+	put(t, writer, "/app/dev", "name: v1")
+	option := Option{Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{}}
+	c, err := NewEtcdConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*EtcdConf).Client.Close()
+	waitName(t, c, "v1")
+	// 监听开始前的修改均被压缩，只能通过全量读取获得
+	put(t, writer, "/app/dev", "name: v2")
+	put(t, writer, "/other", "x")
+	revision := put(t, writer, "/app/dev", "name: v3")
+	if _, err := writer.Compact(ctx, revision); err != nil {
+		t.Fatal(err)
+	}
+	go c.PeriodicUpdate(ctx, option)
+	waitName(t, c, "v3")
+	if got := c.(*EtcdConf).Revision; got < revision {
+		t.Fatalf("revision = %d, want >= %d", got, revision)
+	}
+	put(t, writer, "/app/dev", "name: v4")
+	waitName(t, c, "v4")
+}
+
+func TestEtcdRewatchAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := startEtcd(t, "http", nil)
+	writer := e.dial(clientv3.Config{})
+	put(t, writer, "/app/dev", "name: v1")
+	option := Option{Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{}}
+	c, err := NewEtcdConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*EtcdConf).Client.Close()
+	go c.PeriodicUpdate(ctx, option)
+	put(t, writer, "/app/dev", "name: v2")
+	waitName(t, c, "v2")
+	// 服务重启后监听中断，从最后处理的版本重新监听
+	e.stop()
+	e.start()
+	put(t, writer, "/app/dev", "name: v3")
+	waitName(t, c, "v3")
+}
+
+func TestEtcdAuth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := startEtcd(t, "http", nil)
+	admin := e.dial(clientv3.Config{})
+	put(t, admin, "/app/dev", "name: v1")
+	if _, err := admin.UserAdd(ctx, "root", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.UserGrantRole(ctx, "root", "root"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.AuthEnable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	option := Option{
+		Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{},
+		Auth: true, Username: "root", Password: "secret",
+	}
+	c, err := NewEtcdConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*EtcdConf).Client.Close()
+	waitName(t, c, "v1")
+	go c.PeriodicUpdate(ctx, option)
+	writer := e.dial(clientv3.Config{Username: "root", Password: "secret"})
+	put(t, writer, "/app/dev", "name: v2")
+	waitName(t, c, "v2")
+	// 密码错误时无法创建客户端
+	option.Password = "wrong"
+	option.Bind = &nameConf{}
+	if _, err := NewEtcdConf(ctx, option); err == nil {
+		t.Fatal("NewEtcdConf with wrong password succeeded")
+	}
+}
+
+// 生成自签名的根证书与服务端证书
+func writeCerts(t *testing.T, dir string) (string, string, string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "box-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, kind string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: data}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	return write("ca.pem", "CERTIFICATE", caDER), write("server.pem", "CERTIFICATE", der), write("server-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestEtcdTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	caFile, certFile, keyFile := writeCerts(t, t.TempDir())
+	e := startEtcd(t, "https", func(cfg *embed.Config) {
+		cfg.ClientTLSInfo.CertFile = certFile
+		cfg.ClientTLSInfo.KeyFile = keyFile
+	})
+	tlsConf, err := transport.TLSInfo{TrustedCAFile: caFile}.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := e.dial(clientv3.Config{TLS: tlsConf})
+	put(t, writer, "/app/dev", "name: v1")
+	option := Option{
+		Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{},
+		CAFile: caFile,
+	}
+	c, err := NewEtcdConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*EtcdConf).Client.Close()
+	waitName(t, c, "v1")
+	go c.PeriodicUpdate(ctx, option)
+	put(t, writer, "/app/dev", "name: v2")
+	waitName(t, c, "v2")
+	// 证书文件不存在时直接报错
+	option.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := NewEtcdConf(ctx, option); err == nil {
+		t.Fatal("NewEtcdConf with missing CA succeeded")
+	}
+}
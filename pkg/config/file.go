@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+const (
+	DEFAULT_FILE_DEBOUNCE = 100 * time.Millisecond // 文件变化后等待写入完成的时间
+)
+
+// Conf 结构体
+// 基于本地文件
+type FileConf struct {
+	store
+	Kernel *viper.Viper
+	Path   string
+}
+
+// 创建基于本地文件的配置对象
+func NewFileConf(ctx context.Context, option Option) (Conf, error) {
+	if utf8.RuneCountInString(option.Host) == 0 {
+		return nil, logger.NewError(logger.PVERROR, "路径为必填项", nil)
+	}
+	path, err := filepath.Abs(option.Host)
+	if err != nil {
+		return nil, logger.NewError(logger.PVERROR, "路径有误", err)
+	}
+	vip := viper.New()
+	vip.SetConfigFile(path)
+	vip.SetConfigType(option.Type)
+	// 读取文件并映射到结构体
+	err = vip.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+	c := &FileConf{Kernel: vip, Path: path}
+	c.stamp()
+	if _, err := c.apply(ctx, vip.AllSettings(), option); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 监听文件变化并更新
+// 监听所在目录以兼容编辑器先写临时文件再重命名的保存方式
+// 连续的写入事件合并后再读取，避免读到写了一半的文件
+func (c *FileConf) PeriodicUpdate(ctx context.Context, option Option) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		report(ctx, "监听配置文件失败", logger.NewError(logger.RWARN, "创建文件监听失败", err))
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(c.Path)); err != nil {
+		report(ctx, "监听配置文件失败", logger.NewError(logger.RWARN, "监听目录失败", err))
+		return
+	}
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pending:
+			pending = nil
+			settings, err := c.reload(ctx)
+			if err != nil {
+				report(ctx, "读取配置失败", err)
+				continue
+			}
+			c.update(ctx, settings, option)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != c.Path {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			pending = time.After(DEFAULT_FILE_DEBOUNCE)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			report(ctx, "监听配置文件出错", logger.NewError(logger.RWARN, "文件监听出错", err))
+		}
+	}
+}
+
+// 重新读取文件
+func (c *FileConf) reload(ctx context.Context) (map[string]interface{}, error) {
+	if err := c.Kernel.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	c.stamp()
+	return c.Kernel.AllSettings(), nil
+}
+
+// 以文件修改时间作为来源版本
+func (c *FileConf) stamp() {
+	if info, err := os.Stat(c.Path); err == nil {
+		c.track(info.ModTime().Format(time.RFC3339Nano))
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+func writeConf(t *testing.T, path string, name string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("name: "+name+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	writeConf(t, path, "v1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	option := Option{Host: path, Type: "yaml", Bind: &nameConf{}}
+	c, err := NewFileConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes int32
+	c.OnChange(func(old, new interface{}, diff []Change) {
+		atomic.AddInt32(&changes, 1)
+	})
+	go c.PeriodicUpdate(ctx, option)
+	// 等待监听生效
+	time.Sleep(100 * time.Millisecond)
+	writeConf(t, path, "v2")
+	waitName(t, c, "v2")
+	// 先写临时文件再重命名
+	tmp := filepath.Join(dir, ".app.yaml.tmp")
+	writeConf(t, tmp, "v3")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitName(t, c, "v3")
+	// 同目录下的其他文件不触发更新
+	writeConf(t, filepath.Join(dir, "other.yaml"), "other")
+	// 连续写入合并为一次更新
+	atomic.StoreInt32(&changes, 0)
+	for i := 4; i <= 8; i++ {
+		writeConf(t, path, fmt.Sprintf("v%d", i))
+		time.Sleep(DEFAULT_FILE_DEBOUNCE / 5)
+	}
+	waitName(t, c, "v8")
+	time.Sleep(2 * DEFAULT_FILE_DEBOUNCE)
+	if n := atomic.LoadInt32(&changes); n != 1 {
+		t.Errorf("callbacks = %d, want 1", n)
+	}
+	// 内容有误时沿用上一次的配置
+	if err := os.WriteFile(path, []byte("name: [v9"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * DEFAULT_FILE_DEBOUNCE)
+	if got := c.Current().(*nameConf).Name; got != "v8" {
+		t.Errorf("name = %q, want v8 kept", got)
+	}
+}
+
+// 无法监听时以RWARN报告并退出
+func TestFileWatchError(t *testing.T) {
+	if logger.Initialized() {
+		t.Skip("logger already initialized")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf", "app.yaml")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeConf(t, path, "v1")
+	option := Option{Host: path, Type: "yaml", Bind: &nameConf{}}
+	c, err := NewFileConf(context.Background(), option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.Dir(path))
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.PeriodicUpdate(context.Background(), option)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PeriodicUpdate should return when the directory cannot be watched")
+	}
+	code := fmt.Sprintf("[%d]", logger.RWARN)
+	if out := buf.String(); !strings.Contains(out, "监听配置文件失败") || !strings.Contains(out, code) || !strings.Contains(out, "监听目录失败") {
+		t.Errorf("log = %q, want RWARN report", out)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+const (
+	FILE_REFERENCE_PREFIX = "@file:" // 以文件内容作为值
+)
+
+// 引用格式为${name}或${name:-default}，$${表示字面量${
+var referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+var referenceValue = regexp.MustCompile(`^\$\{([^}]*)\}$`)
+
+// 展开配置中的引用
+// ${name}优先引用其他配置项，不存在时读取环境变量，均不存在时使用默认值
+// 值恰为一个配置项引用时保留被引用值的类型
+// 值以@file:开头时读取文件内容，去除末尾换行
+func interpolate(settings map[string]interface{}) (map[string]interface{}, error) {
+	if !hasReference(settings) {
+		return settings, nil
+	}
+	r := &resolver{flat: flatten("", settings), resolved: map[string]interface{}{}}
+	result, err := r.walk("", settings)
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]interface{}), nil
+}
+
+// 是否包含引用
+func hasReference(settings interface{}) bool {
+	switch v := settings.(type) {
+	case string:
+		return strings.Contains(v, "${") || strings.HasPrefix(v, FILE_REFERENCE_PREFIX)
+	case map[string]interface{}:
+		for _, item := range v {
+			if hasReference(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasReference(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type resolver struct {
+	flat     map[string]interface{}
+	resolved map[string]interface{}
+}
+
+// 遍历配置，path为当前位置以点号连接的键
+func (r *resolver) walk(path string, settings interface{}) (interface{}, error) {
+	switch v := settings.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			sub := strings.ToLower(key)
+			if path != "" {
+				sub = path + "." + sub
+			}
+			val, err := r.walk(sub, item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = val
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			val, err := r.walk("", item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = val
+		}
+		return result, nil
+	case string:
+		if _, ok := r.flat[path]; ok && path != "" {
+			return r.value(path, nil)
+		}
+		return r.expand(v, nil)
+	default:
+		return v, nil
+	}
+}
+
+// 展开配置项的值，stack为引用链，用于检测循环引用
+func (r *resolver) value(key string, stack []string) (interface{}, error) {
+	if val, ok := r.resolved[key]; ok {
+		return val, nil
+	}
+	for i, item := range stack {
+		if item == key {
+			chain := append(append([]string{}, stack[i:]...), key)
+			return nil, logger.NewError(logger.PVERROR, fmt.Sprintf("配置引用存在循环: %s", strings.Join(chain, " -> ")), nil)
+		}
+	}
+	stack = append(stack, key)
+	var val interface{} = r.flat[key]
+	var err error
+	switch v := val.(type) {
+	case string:
+		val, err = r.expand(v, stack)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				if result[i], err = r.expand(s, stack); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			result[i] = item
+		}
+		val = result
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[key] = val
+	return val, nil
+}
+
+// 展开字符串中的引用
+func (r *resolver) expand(text string, stack []string) (interface{}, error) {
+	if strings.HasPrefix(text, FILE_REFERENCE_PREFIX) {
+		content, err := os.ReadFile(strings.TrimPrefix(text, FILE_REFERENCE_PREFIX))
+		if err != nil {
+			return nil, logger.NewError(logger.PVERROR, "读取引用的文件失败", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	if match := referenceValue.FindStringSubmatch(text); match != nil {
+		return r.lookup(match[1], stack)
+	}
+	var ers error
+	result := referencePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		if ers != nil {
+			return match
+		}
+		val, err := r.lookup(match[2:len(match)-1], stack)
+		if err != nil {
+			ers = err
+			return match
+		}
+		return fmt.Sprint(val)
+	})
+	if ers != nil {
+		return nil, ers
+	}
+	return result, nil
+}
+
+// 解析单个引用
+// 环境变量为空时同样使用默认值，与shell的:-一致
+func (r *resolver) lookup(expr string, stack []string) (interface{}, error) {
+	name, def, hasDefault := expr, "", false
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, hasDefault = expr[:i], expr[i+2:], true
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, logger.NewError(logger.PVERROR, fmt.Sprintf("配置引用%s格式有误", expr), nil)
+	}
+	if _, ok := r.flat[strings.ToLower(name)]; ok {
+		return r.value(strings.ToLower(name), stack)
+	}
+	if val, ok := os.LookupEnv(name); ok && (val != "" || !hasDefault) {
+		return val, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return nil, logger.NewError(logger.PVERROR, fmt.Sprintf("配置引用%s未定义", name), nil)
+}
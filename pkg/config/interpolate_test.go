@@ -0,0 +1,132 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	writeFile(t, secret, "s3cret\r\n")
+	t.Setenv("BOX_TEST_HOST", "env-host")
+	t.Setenv("BOX_TEST_EMPTY", "")
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		key      string
+		want     interface{}
+	}{
+		{
+			name:     "keeps type of a whole reference",
+			settings: map[string]interface{}{"port": 8080, "addr": "${port}"},
+			key:      "addr", want: 8080,
+		},
+		{
+			name:     "embedded references",
+			settings: map[string]interface{}{"host": "h", "port": 80, "url": "http://${host}:${port}/"},
+			key:      "url", want: "http://h:80/",
+		},
+		{
+			name: "nested key",
+			settings: map[string]interface{}{
+				"server": map[string]interface{}{"host": "x"},
+				"db":     map[string]interface{}{"host": "${server.host}"},
+			},
+			key: "db", want: map[string]interface{}{"host": "x"},
+		},
+		{
+			name:     "reference chain",
+			settings: map[string]interface{}{"a": "${b}", "b": "${c}", "c": "end"},
+			key:      "a", want: "end",
+		},
+		{
+			name:     "list items",
+			settings: map[string]interface{}{"host": "h", "hosts": []interface{}{"${host}", 1}},
+			key:      "hosts", want: []interface{}{"h", 1},
+		},
+		{
+			name:     "environment",
+			settings: map[string]interface{}{"host": "${BOX_TEST_HOST}"},
+			key:      "host", want: "env-host",
+		},
+		{
+			name:     "default when undefined",
+			settings: map[string]interface{}{"host": "${BOX_TEST_UNDEFINED:-fallback}"},
+			key:      "host", want: "fallback",
+		},
+		{
+			name:     "default when empty",
+			settings: map[string]interface{}{"host": "${BOX_TEST_EMPTY:-fallback}"},
+			key:      "host", want: "fallback",
+		},
+		{
+			name:     "empty without default",
+			settings: map[string]interface{}{"host": "${BOX_TEST_EMPTY}"},
+			key:      "host", want: "",
+		},
+		{
+			name:     "config before environment and default",
+			settings: map[string]interface{}{"name": "n", "value": "${name:-fallback}"},
+			key:      "value", want: "n",
+		},
+		{
+			name:     "escape",
+			settings: map[string]interface{}{"port": 1, "text": "$${HOME} and $${port} ${port}"},
+			key:      "text", want: "${HOME} and ${port} 1",
+		},
+		{
+			name:     "file",
+			settings: map[string]interface{}{"password": FILE_REFERENCE_PREFIX + secret},
+			key:      "password", want: "s3cret",
+		},
+	}
+	for _, tt := range tests {
+		result, err := interpolate(tt.settings)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := result[tt.key]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %s = %#v, want %#v", tt.name, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     string
+	}{
+		{name: "self cycle", settings: map[string]interface{}{"a": "${a}"}, want: "a -> a"},
+		{name: "cycle", settings: map[string]interface{}{"a": "x${b}", "b": "${c}", "c": "${a}"}, want: "循环"},
+		{name: "missing", settings: map[string]interface{}{"a": "${BOX_TEST_UNDEFINED}"}, want: "BOX_TEST_UNDEFINED未定义"},
+		{name: "missing in text", settings: map[string]interface{}{"a": "x ${BOX_TEST_UNDEFINED} y"}, want: "未定义"},
+		{name: "empty name", settings: map[string]interface{}{"a": "${:-x}"}, want: "格式有误"},
+		{name: "missing file", settings: map[string]interface{}{"a": FILE_REFERENCE_PREFIX + filepath.Join(t.TempDir(), "missing")}, want: "读取引用的文件失败"},
+	}
+	for _, tt := range tests {
+		_, err := interpolate(tt.settings)
+		if err == nil {
+			t.Errorf("%s: interpolate succeeded", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %q, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// 不含引用时原样返回
+func TestInterpolatePlain(t *testing.T) {
+	settings := map[string]interface{}{"a": "$HOME", "b": 1}
+	result, err := interpolate(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, settings) {
+		t.Errorf("interpolate = %v, want %v", result, settings)
+	}
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// 配置来源，按优先级由低到高排列
+type SourceSupport string
+
+const (
+	SOURCE_DEFAULT SourceSupport = "default"
+	SOURCE_FILE    SourceSupport = "file"
+	SOURCE_REMOTE  SourceSupport = "remote"
+	SOURCE_ENV     SourceSupport = "env"
+	SOURCE_FLAG    SourceSupport = "flag"
+)
+
+const (
+	LAYERED TypeSupport = "layered"
+	// 定义默认值
+	DEFAULT_ENV_PREFIX = "APP"
+)
+
+// 分层配置所用参数
+type LayerOption struct {
+	File      string      `json:"file" label:"本地文件路径" desc:"为空时不读取本地文件"`
+	Remote    TypeSupport `json:"remote" label:"远端类型" desc:"为空时不读取远端，连接参数沿用Option"`
+	EnvPrefix string      `json:"env_prefix" label:"环境变量前缀" desc:"默认为APP，例如APP_SECTION_KEY" default:"APP"`
+	Overrides Overrides   `json:"overrides" label:"命令行覆盖项" desc:"格式为section.key=value"`
+}
+
+// 命令行覆盖项
+// 可通过flag.Var(&overrides, "set", "...")注册，参数可重复
+type Overrides []string
+
+func (c *Overrides) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *Overrides) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return logger.NewError(logger.PVERROR, "覆盖项格式应为key=value", nil)
+	}
+	*c = append(*c, v)
+	return nil
+}
+
+// 配置项及其来源
+type Origin struct {
+	Key    string        `json:"key" label:"键名"`
+	Value  interface{}   `json:"value" label:"值"`
+	Source SourceSupport `json:"source" label:"来源"`
+}
+
+// 可独立监听变化的配置层
+type source interface {
+	Conf
+	settings() map[string]interface{}
+}
+
+// 配置层及其创建时所用参数
+type layer struct {
+	kernel source
+	option Option
+	src    SourceSupport
+}
+
+// Conf 结构体
+// 按 结构体默认值 < 本地文件 < 远端 < 环境变量 < 命令行 的顺序合并
+type LayeredConf struct {
+	store
+	Kernel   *viper.Viper
+	Layer    LayerOption
+	defaults map[string]interface{}
+	file     *layer
+	remote   *layer
+	guard    sync.RWMutex
+	origins  map[string]Origin
+}
+
+// 创建分层配置对象
+func NewLayeredConf(ctx context.Context, option Option) (Conf, error) {
+	layered := option.Layer
+	if utf8.RuneCountInString(layered.EnvPrefix) == 0 {
+		layered.EnvPrefix = DEFAULT_ENV_PREFIX
+	}
+	c := &LayeredConf{Layer: layered, defaults: map[string]interface{}{}}
+	// 结构体当前的值作为默认值，键名与映射时使用同一标签
+	if option.Bind != nil {
+		tag := option.Tag
+		if tag == "" {
+			tag = TAG_MAPSTRUCTURE
+		}
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{TagName: string(tag), Result: &c.defaults})
+		if err != nil {
+			return nil, logger.NewError(logger.EDERROR, "默认值解析失败", err)
+		}
+		if err := decoder.Decode(option.Bind); err != nil {
+			return nil, logger.NewError(logger.EDERROR, "默认值解析失败", err)
+		}
+	}
+	if utf8.RuneCountInString(layered.File) > 0 {
+		sub := option
+		sub.Bind = nil
+		sub.Host = layered.File
+		// 有扩展名时按扩展名解析
+		if filepath.Ext(layered.File) != "" {
+			sub.Type = ""
+		}
+		sub.Snapshot = ""
+		f, err := NewFileConf(ctx, sub)
+		if err != nil {
+			return nil, err
+		}
+		c.file = &layer{kernel: f.(source), option: sub, src: SOURCE_FILE}
+	}
+	if utf8.RuneCountInString(string(layered.Remote)) > 0 {
+		if layered.Remote == LAYERED {
+			return nil, logger.NewError(logger.PVERROR, "远端类型有误", nil)
+		}
+		sub := option
+		sub.Bind = nil
+		r, err := ConfFactory(ctx, layered.Remote, sub)
+		if err != nil {
+			return nil, err
+		}
+		c.remote = &layer{kernel: r.(source), option: sub, src: SOURCE_REMOTE}
+	}
+	if err := c.compose(ctx, option); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 远端使用本地快照时整体视为过期，远端监听恢复后清除
+func (c *LayeredConf) Stale() bool {
+	return c.remote != nil && c.remote.kernel.Stale()
+}
+
+// 已启用的文件与远端层
+func (c *LayeredConf) layers() []*layer {
+	result := []*layer{}
+	for _, item := range []*layer{c.file, c.remote} {
+		if item != nil {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// 按各层当前生效的配置合并
+func (c *LayeredConf) compose(ctx context.Context, option Option) error {
+	origins := map[string]Origin{}
+	merge := func(settings map[string]interface{}, src SourceSupport) {
+		for key, val := range flatten("", settings) {
+			origins[key] = Origin{Key: key, Value: val, Source: src}
+		}
+	}
+	merge(c.defaults, SOURCE_DEFAULT)
+	for _, item := range c.layers() {
+		merge(item.kernel.settings(), item.src)
+	}
+	// 环境变量仅覆盖已知的键，键名中的点号替换为下划线
+	for key := range origins {
+		name := strings.ToUpper(fmt.Sprintf("%s_%s", c.Layer.EnvPrefix, strings.ReplaceAll(key, ".", "_")))
+		if val, ok := os.LookupEnv(name); ok {
+			origins[key] = Origin{Key: key, Value: val, Source: SOURCE_ENV}
+		}
+	}
+	for _, item := range c.Layer.Overrides {
+		pair := strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			return logger.NewError(logger.PVERROR, "覆盖项格式应为key=value", nil)
+		}
+		key := strings.ToLower(strings.TrimSpace(pair[0]))
+		origins[key] = Origin{Key: key, Value: pair[1], Source: SOURCE_FLAG}
+	}
+	nested := map[string]interface{}{}
+	for key, origin := range origins {
+		expand(nested, key, origin.Value)
+	}
+	// 快照由远端层写入，合并结果不再重复写入
+	option.Snapshot = ""
+	// 来源版本优先取远端
+	for _, item := range []*layer{c.remote, c.file} {
+		if item != nil && item.kernel.SourceRevision() != "" {
+			c.track(item.kernel.SourceRevision())
+			break
+		}
+	}
+	if _, err := c.apply(ctx, nested, option); err != nil {
+		return err
+	}
+	c.guard.Lock()
+	c.Kernel = c.load().kernel
+	c.origins = origins
+	c.guard.Unlock()
+	return nil
+}
+
+// 生效的配置项及其来源，按键名排序
+func (c *LayeredConf) Explain() []Origin {
+	c.guard.RLock()
+	defer c.guard.RUnlock()
+	result := make([]Origin, 0, len(c.origins))
+	for _, origin := range c.origins {
+		result = append(result, origin)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// 输出生效的配置项及其来源
+func (c *LayeredConf) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, origin := range c.Explain() {
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", origin.Key, origin.Value, origin.Source)
+	}
+	return tw.Flush()
+}
+
+// 由文件与远端各自的监听驱动更新，任一层变化后重新合并
+// 远端为Consul时使用阻塞查询，为Etcd时使用监听接口
+func (c *LayeredConf) PeriodicUpdate(ctx context.Context, option Option) {
+	changed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, item := range c.layers() {
+		item.kernel.OnChange(func(old, new interface{}, diff []Change) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		wg.Add(1)
+		go func(item *layer) {
+			defer wg.Done()
+			item.kernel.PeriodicUpdate(ctx, item.option)
+		}(item)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			if err := c.compose(ctx, option); err != nil {
+				report(ctx, "配置更新未生效，沿用上一次的配置", err)
+			}
+		}
+	}
+}
+
+// 将嵌套结构展开为以点号连接的键
+func flatten(prefix string, settings map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, val := range settings {
+		key = strings.ToLower(key)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if sub, ok := val.(map[string]interface{}); ok && len(sub) > 0 {
+			for k, v := range flatten(key, sub) {
+				result[k] = v
+			}
+			continue
+		}
+		result[key] = val
+	}
+	return result
+}
+
+// 按点号连接的键写回嵌套结构
+func expand(nested map[string]interface{}, key string, val interface{}) {
+	parts := strings.Split(key, ".")
+	current := nested
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := current[part].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			current[part] = sub
+		}
+		current = sub
+	}
+	current[parts[len(parts)-1]] = val
+}
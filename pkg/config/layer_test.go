@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type serverConf struct {
+	HostName string `mapstructure:"host_name" json:"host"`
+	Port     int    `mapstructure:"port" json:"port"`
+	Mode     string `mapstructure:"mode" json:"mode"`
+	Level    string `mapstructure:"level" json:"level"`
+	Debug    bool   `mapstructure:"debug" json:"debug"`
+}
+
+type layerConf struct {
+	Server serverConf `mapstructure:"server" json:"server"`
+}
+
+func defaultLayerConf() *layerConf {
+	return &layerConf{Server: serverConf{HostName: "localhost", Port: 80, Mode: "default", Level: "default"}}
+}
+
+func waitFor(t *testing.T, ok func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if ok() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sources(c *LayeredConf) map[string]SourceSupport {
+	result := map[string]SourceSupport{}
+	for _, origin := range c.Explain() {
+		result[origin.Key] = origin.Source
+	}
+	return result
+}
+
+// 各层依次覆盖：结构体默认值 < 本地文件 < 远端 < 环境变量 < 命令行
+func TestLayeredPrecedence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := startEtcd(t, "http", nil)
+	writer := e.dial(clientv3.Config{})
+	put(t, writer, "/app/dev", "server: {mode: remote, level: remote, debug: false}")
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	writeFile(t, file, "server: {port: 9000, mode: file, level: file}")
+	t.Setenv("APP_SERVER_LEVEL", "env")
+	t.Setenv("APP_SERVER_DEBUG", "false")
+	option := Option{
+		Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: defaultLayerConf(),
+		Layer: LayerOption{File: file, Remote: ETCD, Overrides: Overrides{"server.debug=true"}},
+	}
+	c, err := NewLayeredConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Current().(*layerConf).Server
+	want := serverConf{HostName: "localhost", Port: 9000, Mode: "remote", Level: "env", Debug: true}
+	if got != want {
+		t.Fatalf("Current = %+v, want %+v", got, want)
+	}
+	wantSources := map[string]SourceSupport{
+		"server.host_name": SOURCE_DEFAULT,
+		"server.port":      SOURCE_FILE,
+		"server.mode":      SOURCE_REMOTE,
+		"server.level":     SOURCE_ENV,
+		"server.debug":     SOURCE_FLAG,
+	}
+	gotSources := sources(c.(*LayeredConf))
+	if len(gotSources) != len(wantSources) {
+		t.Fatalf("Explain = %v, want %v", gotSources, wantSources)
+	}
+	for key, src := range wantSources {
+		if gotSources[key] != src {
+			t.Errorf("source of %s = %q, want %q", key, gotSources[key], src)
+		}
+	}
+	if c.Stale() {
+		t.Error("Stale = true, want false")
+	}
+}
+
+// 默认值的键名与映射所用标签一致，未被覆盖的字段保留默认值
+func TestLayeredDefaultsTag(t *testing.T) {
+	for _, item := range []struct {
+		tag TagSupport
+		key string
+	}{
+		{tag: "", key: "server.host_name"},
+		{tag: TAG_MAPSTRUCTURE, key: "server.host_name"},
+		{tag: TAG_JSON, key: "server.host"},
+	} {
+		option := Option{Application: "app", Env: "dev", Type: "yaml", Bind: defaultLayerConf(), Tag: item.tag}
+		c, err := NewLayeredConf(context.Background(), option)
+		if err != nil {
+			t.Fatalf("tag %q: %v", item.tag, err)
+		}
+		if got := c.Current().(*layerConf).Server; got != defaultLayerConf().Server {
+			t.Errorf("tag %q: Current = %+v, want defaults", item.tag, got)
+		}
+		if src := sources(c.(*LayeredConf))[item.key]; src != SOURCE_DEFAULT {
+			t.Errorf("tag %q: source of %s = %q, want default", item.tag, item.key, src)
+		}
+	}
+}
+
+// 远端的监听与文件的监听各自驱动重新合并
+func TestLayeredWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := startEtcd(t, "http", nil)
+	writer := e.dial(clientv3.Config{})
+	put(t, writer, "/app/dev", "server: {mode: v1}")
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	writeFile(t, file, "server: {port: 9000}")
+	t.Setenv("APP_SERVER_LEVEL", "env")
+	option := Option{
+		Host: e.endpoint(), Application: "app", Env: "dev", Type: "yaml", Bind: defaultLayerConf(),
+		Layer: LayerOption{File: file, Remote: ETCD},
+	}
+	c, err := NewLayeredConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan []Change, 10)
+	c.OnChange(func(old, new interface{}, diff []Change) {
+		changes <- diff
+	})
+	done := make(chan struct{})
+	go func() {
+		c.PeriodicUpdate(ctx, option)
+		close(done)
+	}()
+	current := func() serverConf {
+		return c.Current().(*layerConf).Server
+	}
+	revision := put(t, writer, "/app/dev", "server: {mode: v2, level: remote}")
+	waitFor(t, func() bool { return current().Mode == "v2" })
+	// 环境变量仍优先于远端
+	if got := current().Level; got != "env" {
+		t.Errorf("level = %q, want env", got)
+	}
+	if got, want := c.SourceRevision(), strconv.FormatInt(revision, 10); got != want {
+		t.Errorf("SourceRevision = %q, want %s", got, want)
+	}
+	writeFile(t, file, "server: {port: 9100}")
+	waitFor(t, func() bool { return current().Port == 9100 })
+	if src := sources(c.(*LayeredConf))["server.port"]; src != SOURCE_FILE {
+		t.Errorf("source of server.port = %q, want file", src)
+	}
+	if len(changes) != 2 {
+		t.Errorf("callbacks = %d, want 2", len(changes))
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PeriodicUpdate did not return after cancel")
+	}
+}
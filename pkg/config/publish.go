@@ -0,0 +1,164 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/spf13/viper"
+)
+
+const (
+	DEFAULT_HISTORY_SUFFIX = ".history" // 历史版本的键名后缀，与配置键同级
+	DEFAULT_HISTORY_SIZE   = 20         // 默认保留的历史版本数量
+)
+
+// 发布与回滚配置
+type Publisher interface {
+	Publish(ctx context.Context, app string, env string, doc []byte) (int, error)
+	Rollback(ctx context.Context, app string, env string, version int) (int, error)
+	History(ctx context.Context, app string, env string) ([]Release, error)
+}
+
+// 已发布的版本
+type Release struct {
+	Version   int       `json:"version" label:"版本号"`
+	Timestamp time.Time `json:"timestamp" label:"发布时间"`
+	Document  string    `json:"document" label:"配置内容"`
+	Remark    string    `json:"remark" label:"备注" desc:"回滚时记录来源版本"`
+}
+
+// 校验并发布配置
+// 配置与历史版本在同一事务中按check-and-set写入，期间被他人修改时返回错误
+func (c *ConsulConf) Publish(ctx context.Context, app string, env string, doc []byte) (int, error) {
+	return c.publish(ctx, consulKey(app, env), doc, "")
+}
+
+// 回滚至指定版本
+// 回滚以新版本发布，历史中保留完整记录
+func (c *ConsulConf) Rollback(ctx context.Context, app string, env string, version int) (int, error) {
+	key := consulKey(app, env)
+	releases, _, err := c.history(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	for _, release := range releases {
+		if release.Version == version {
+			return c.publish(ctx, key, []byte(release.Document), fmt.Sprintf("回滚至版本%d", version))
+		}
+	}
+	return 0, logger.NewError(logger.CHECK, fmt.Sprintf("版本%d不存在", version), nil)
+}
+
+// 已发布的版本，按版本号升序
+func (c *ConsulConf) History(ctx context.Context, app string, env string) ([]Release, error) {
+	releases, _, err := c.history(ctx, consulKey(app, env))
+	return releases, err
+}
+
+func (c *ConsulConf) publish(ctx context.Context, key string, doc []byte, remark string) (int, error) {
+	if err := c.check(ctx, doc); err != nil {
+		return 0, err
+	}
+	query := (&api.QueryOptions{}).WithContext(ctx)
+	current, _, err := c.Client.KV().Get(key, query)
+	if err != nil {
+		return 0, logger.NewError(logger.CALLERROR, "读取Consul失败", err)
+	}
+	releases, index, err := c.history(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	// 首次发布时记录已有的配置，以便回滚
+	if len(releases) == 0 && current != nil {
+		releases = append(releases, Release{Version: 1, Timestamp: time.Now(), Document: string(current.Value)})
+	}
+	version := 1
+	if len(releases) > 0 {
+		version = releases[len(releases)-1].Version + 1
+	}
+	releases = append(releases, Release{Version: version, Timestamp: time.Now(), Document: string(doc), Remark: remark})
+	if len(releases) > DEFAULT_HISTORY_SIZE {
+		releases = releases[len(releases)-DEFAULT_HISTORY_SIZE:]
+	}
+	content, err := json.Marshal(releases)
+	if err != nil {
+		return 0, logger.NewError(logger.EDERROR, "历史版本编码失败", err)
+	}
+	// 索引为0时表示仅在键不存在时写入
+	var modify uint64
+	if current != nil {
+		modify = current.ModifyIndex
+	}
+	ops := api.KVTxnOps{
+		&api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: doc, Index: modify},
+		&api.KVTxnOp{Verb: api.KVCAS, Key: key + DEFAULT_HISTORY_SUFFIX, Value: content, Index: index},
+	}
+	ok, res, _, err := c.Client.KV().Txn(ops, query)
+	if err != nil {
+		return 0, logger.NewError(logger.CALLERROR, "写入Consul失败", err)
+	}
+	if !ok {
+		message := "配置已被修改，请重新发布"
+		if res != nil && len(res.Errors) > 0 {
+			message = fmt.Sprintf("%s: %s", message, res.Errors[0].What)
+		}
+		return 0, logger.NewError(logger.CHECK, message, nil)
+	}
+	return version, nil
+}
+
+// 读取历史版本及其修改索引
+func (c *ConsulConf) history(ctx context.Context, key string) ([]Release, uint64, error) {
+	pair, _, err := c.Client.KV().Get(key+DEFAULT_HISTORY_SUFFIX, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, 0, logger.NewError(logger.CALLERROR, "读取Consul失败", err)
+	}
+	releases := []Release{}
+	if pair == nil {
+		return releases, 0, nil
+	}
+	if err := json.Unmarshal(pair.Value, &releases); err != nil {
+		return nil, 0, logger.NewError(logger.EDERROR, "历史版本解析失败", err)
+	}
+	return releases, pair.ModifyIndex, nil
+}
+
+// 按绑定的结构体类型解析并校验配置
+func (c *ConsulConf) check(ctx context.Context, doc []byte) error {
+	vip := viper.New()
+	vip.SetConfigType(c.option.Type)
+	if err := vip.ReadConfig(bytes.NewReader(doc)); err != nil {
+		return logger.NewError(logger.EDERROR, "配置解析失败", err)
+	}
+	var target interface{}
+	if c.option.Bind != nil {
+		if target = fresh(c.option.Bind); target == nil {
+			return logger.NewError(logger.PVERROR, "用于映射配置的结构体必须为指针", nil)
+		}
+	}
+	_, err := bind(ctx, vip.AllSettings(), c.option, target)
+	return err
+}
+
+// 发布配置，仅支持Consul
+func Publish(ctx context.Context, app string, env string, doc []byte) (int, error) {
+	c, ok := conf.(Publisher)
+	if !ok {
+		return 0, logger.NewError(logger.PVERROR, "当前配置类型不支持发布", nil)
+	}
+	return c.Publish(ctx, app, env, doc)
+}
+
+// 回滚配置，仅支持Consul
+func Rollback(ctx context.Context, app string, env string, version int) (int, error) {
+	c, ok := conf.(Publisher)
+	if !ok {
+		return 0, logger.NewError(logger.PVERROR, "当前配置类型不支持回滚", nil)
+	}
+	return c.Rollback(ctx, app, env, version)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/hashicorp/consul/api"
+)
+
+func newPublisher(t *testing.T, value string) (*fakeConsul, *ConsulConf) {
+	t.Helper()
+	f := &fakeConsul{kv: map[string]*api.KVPair{}}
+	f.put("app/dev", value)
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	option := Option{Host: srv.URL, Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{}}
+	c, err := NewConsulConf(context.Background(), option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, c.(*ConsulConf)
+}
+
+func versions(t *testing.T, c *ConsulConf) []string {
+	t.Helper()
+	releases, err := c.History(context.Background(), "app", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := []string{}
+	for _, release := range releases {
+		result = append(result, release.Document)
+	}
+	return result
+}
+
+// 读取后配置或历史被他人修改时，事务整体失败且不覆盖他人的修改
+func TestPublishConflict(t *testing.T) {
+	ctx := context.Background()
+	f, c := newPublisher(t, "name: v1")
+	version, err := c.Publish(ctx, "app", "dev", []byte("name: v2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 || f.get("app/dev") != "name: v2" {
+		t.Fatalf("version = %d, value = %q, want 2 and v2", version, f.get("app/dev"))
+	}
+	for _, key := range []string{"app/dev", "app/dev" + DEFAULT_HISTORY_SUFFIX} {
+		f.mu.Lock()
+		f.beforeTxn = func() {
+			f.put(key, f.get(key))
+		}
+		f.mu.Unlock()
+		_, err := c.Publish(ctx, "app", "dev", []byte("name: v3"))
+		ers, ok := err.(*logger.BaseError)
+		if !ok || ers.Code() != logger.CHECK || !strings.Contains(ers.Error(), "配置已被修改") {
+			t.Fatalf("%s modified: err = %v, want conflict", key, err)
+		}
+		if got := f.get("app/dev"); got != "name: v2" {
+			t.Errorf("%s modified: value = %q, want v2", key, got)
+		}
+		if got := versions(t, c); len(got) != 2 {
+			t.Errorf("%s modified: history = %v, want 2 releases", key, got)
+		}
+	}
+	// 校验失败的配置不写入
+	if _, err := c.Publish(ctx, "app", "dev", []byte("name: [")); err == nil {
+		t.Error("Publish with invalid document succeeded")
+	}
+	if got := f.get("app/dev"); got != "name: v2" {
+		t.Errorf("value = %q after invalid publish, want v2", got)
+	}
+}
+
+// 回滚以新版本重新发布历史中的内容
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	f, c := newPublisher(t, "name: v1")
+	for _, doc := range []string{"name: v2", "name: v3"} {
+		if _, err := c.Publish(ctx, "app", "dev", []byte(doc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := c.Rollback(ctx, "app", "dev", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 || f.get("app/dev") != "name: v2" {
+		t.Fatalf("version = %d, value = %q, want 4 and v2", version, f.get("app/dev"))
+	}
+	releases, err := c.History(ctx, "app", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"name: v1", "name: v2", "name: v3", "name: v2"}
+	if len(releases) != len(want) {
+		t.Fatalf("history = %v, want %v", releases, want)
+	}
+	for i, release := range releases {
+		if release.Version != i+1 || release.Document != want[i] {
+			t.Errorf("release %d = %d %q, want %d %q", i, release.Version, release.Document, i+1, want[i])
+		}
+	}
+	if remark := releases[3].Remark; remark != "回滚至版本2" {
+		t.Errorf("remark = %q", remark)
+	}
+	// 回滚至首次发布前已有的配置
+	if _, err := c.Rollback(ctx, "app", "dev", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.get("app/dev"); got != "name: v1" {
+		t.Errorf("value = %q, want v1", got)
+	}
+	if _, err := c.Rollback(ctx, "app", "dev", 99); err == nil {
+		t.Error("Rollback to a missing version succeeded")
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+const (
+	DEFAULT_SECRET_ENV = "BOX_CONFIG_KEY" // 默认的密钥环境变量
+	SECRET_KEY_SIZE    = 32               // 生成密钥的长度，对应AES-256
+)
+
+// 加密值的格式为ENC(base64)，base64内容为 nonce | 密文
+var secretPattern = regexp.MustCompile(`ENC\(([A-Za-z0-9+/=]+)\)`)
+var secretValue = regexp.MustCompile(`^ENC\(([A-Za-z0-9+/=]+)\)$`)
+
+// 密钥参数
+// 密钥为base64编码，可配置多个以便轮换，加密时使用第一个，解密时依次尝试
+type SecretOption struct {
+	Env  string `json:"env" label:"密钥环境变量" desc:"默认为BOX_CONFIG_KEY，多个密钥以逗号分隔" default:"BOX_CONFIG_KEY"`
+	File string `json:"file" label:"密钥文件路径" desc:"每行一个密钥，填写时优先于环境变量"`
+}
+
+// 读取密钥
+func LoadKeys(option SecretOption) ([][]byte, error) {
+	var raw []string
+	if utf8.RuneCountInString(option.File) > 0 {
+		content, err := os.ReadFile(option.File)
+		if err != nil {
+			return nil, logger.NewError(logger.PVERROR, "读取密钥文件失败", err)
+		}
+		raw = strings.Split(string(content), "\n")
+	} else {
+		if utf8.RuneCountInString(option.Env) == 0 {
+			option.Env = DEFAULT_SECRET_ENV
+		}
+		raw = strings.Split(os.Getenv(option.Env), ",")
+	}
+	return ParseKeys(raw...)
+}
+
+// 解析base64编码的密钥，忽略空行
+func ParseKeys(raw ...string) ([][]byte, error) {
+	keys := [][]byte{}
+	for _, item := range raw {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(item)
+		if err != nil {
+			return nil, logger.NewError(logger.EDERROR, "密钥格式有误", err)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, logger.NewError(logger.PVERROR, "密钥长度应为16、24或32字节", nil)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, logger.NewError(logger.PVERROR, "未配置密钥", nil)
+	}
+	return keys, nil
+}
+
+// 生成base64编码的随机密钥
+func GenerateKey() (string, error) {
+	key := make([]byte, SECRET_KEY_SIZE)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// 加密，返回ENC(base64)格式
+func Encrypt(key []byte, plain string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return "ENC(" + base64.StdEncoding.EncodeToString(sealed) + ")", nil
+}
+
+// 解密单个ENC(base64)格式的值
+func Decrypt(keys [][]byte, value string) (string, error) {
+	match := secretValue.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return "", logger.NewError(logger.PVERROR, "不是加密值", nil)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		return "", logger.NewError(logger.EDERROR, "加密值格式有误", err)
+	}
+	for _, key := range keys {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return "", logger.NewError(logger.EDERROR, "加密值格式有误", nil)
+		}
+		nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		plain, err := gcm.Open(nil, nonce, data, nil)
+		if err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", logger.NewError(logger.EDERROR, "解密失败，密钥不匹配", nil)
+}
+
+// 替换文本中所有的加密值
+// 用于密钥轮换: 以旧密钥解密后用新密钥重新加密
+func Rotate(document string, old [][]byte, key []byte) (string, error) {
+	var ers error
+	result := secretPattern.ReplaceAllStringFunc(document, func(value string) string {
+		if ers != nil {
+			return value
+		}
+		plain, err := Decrypt(old, value)
+		if err != nil {
+			ers = err
+			return value
+		}
+		sealed, err := Encrypt(key, plain)
+		if err != nil {
+			ers = err
+			return value
+		}
+		return sealed
+	})
+	return result, ers
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, logger.NewError(logger.PVERROR, "密钥长度有误", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// 是否包含加密值
+func hasSecret(settings interface{}) bool {
+	switch v := settings.(type) {
+	case string:
+		return secretValue.MatchString(strings.TrimSpace(v))
+	case map[string]interface{}:
+		for _, item := range v {
+			if hasSecret(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasSecret(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 解密配置中所有形如ENC(base64)的字符串值，返回新的配置
+func decryptSettings(settings interface{}, keys [][]byte) (interface{}, error) {
+	switch v := settings.(type) {
+	case string:
+		if !secretValue.MatchString(strings.TrimSpace(v)) {
+			return v, nil
+		}
+		return Decrypt(keys, v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			val, err := decryptSettings(item, keys)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = val
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			val, err := decryptSettings(item, keys)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = val
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
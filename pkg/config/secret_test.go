@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	raw, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseKeys(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys[0]
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, plain := range []string{"", "secret", "密码 with spaces", strings.Repeat("x", 4096)} {
+		sealed, err := Encrypt(key, plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !secretValue.MatchString(sealed) {
+			t.Fatalf("Encrypt = %q, want ENC(...)", sealed)
+		}
+		got, err := Decrypt([][]byte{key}, "  "+sealed+"\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != plain {
+			t.Errorf("Decrypt = %q, want %q", got, plain)
+		}
+	}
+	// 随机nonce，相同明文的密文不同
+	a, _ := Encrypt(key, "same")
+	b, _ := Encrypt(key, "same")
+	if a == b {
+		t.Error("Encrypt returned identical ciphertexts")
+	}
+}
+
+func TestSecretDecryptErrors(t *testing.T) {
+	key := testKey(t)
+	sealed, err := Encrypt(key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(secretValue.FindStringSubmatch(sealed)[1])
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 0x01
+	tests := []struct {
+		name  string
+		keys  [][]byte
+		value string
+	}{
+		{name: "wrong key", keys: [][]byte{testKey(t)}, value: sealed},
+		{name: "tampered", keys: [][]byte{key}, value: "ENC(" + base64.StdEncoding.EncodeToString(tampered) + ")"},
+		{name: "truncated", keys: [][]byte{key}, value: "ENC(" + base64.StdEncoding.EncodeToString(raw[:4]) + ")"},
+		{name: "bad base64", keys: [][]byte{key}, value: "ENC(abc)"},
+		{name: "not encrypted", keys: [][]byte{key}, value: "secret"},
+		{name: "embedded", keys: [][]byte{key}, value: "prefix " + sealed},
+		{name: "bad key", keys: [][]byte{[]byte("short")}, value: sealed},
+	}
+	for _, tt := range tests {
+		if got, err := Decrypt(tt.keys, tt.value); err == nil {
+			t.Errorf("%s: Decrypt = %q, want error", tt.name, got)
+		}
+	}
+}
+
+// 轮换期间同时配置新旧密钥，旧密文仍可解密，Rotate后仅需新密钥
+func TestSecretRotate(t *testing.T) {
+	old, key := testKey(t), testKey(t)
+	a, _ := Encrypt(old, "a")
+	b, _ := Encrypt(old, "b")
+	document := "user: " + a + "\npass: " + b + "\nplain: c\n"
+	for _, keys := range [][][]byte{{key, old}, {old, key}} {
+		if got, err := Decrypt(keys, a); err != nil || got != "a" {
+			t.Fatalf("Decrypt with both keys = %q, %v", got, err)
+		}
+	}
+	rotated, err := Rotate(document, [][]byte{old}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(rotated, a) || strings.Contains(rotated, b) || !strings.Contains(rotated, "plain: c\n") {
+		t.Fatalf("Rotate = %q", rotated)
+	}
+	values := secretPattern.FindAllString(rotated, -1)
+	if len(values) != 2 {
+		t.Fatalf("Rotate kept %d values, want 2", len(values))
+	}
+	for i, want := range []string{"a", "b"} {
+		if got, err := Decrypt([][]byte{key}, values[i]); err != nil || got != want {
+			t.Errorf("Decrypt rotated = %q, %v, want %q", got, err, want)
+		}
+		if _, err := Decrypt([][]byte{old}, values[i]); err == nil {
+			t.Error("old key still decrypts rotated value")
+		}
+	}
+	// 旧密钥不匹配时报错并保持原文
+	if _, err := Rotate(document, [][]byte{testKey(t)}, key); err == nil {
+		t.Error("Rotate with wrong key succeeded")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	encode := func(size int) string {
+		return base64.StdEncoding.EncodeToString(make([]byte, size))
+	}
+	tests := []struct {
+		name string
+		raw  []string
+		want int
+		ok   bool
+	}{
+		{name: "aes-128", raw: []string{encode(16)}, want: 1, ok: true},
+		{name: "aes-192", raw: []string{encode(24)}, want: 1, ok: true},
+		{name: "aes-256", raw: []string{encode(32)}, want: 1, ok: true},
+		{name: "blank lines", raw: []string{"", " " + encode(32) + " ", "\r", encode(16)}, want: 2, ok: true},
+		{name: "bad length", raw: []string{encode(20)}},
+		{name: "bad base64", raw: []string{"not-base64!"}},
+		{name: "empty", raw: []string{"", " "}},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		keys, err := ParseKeys(tt.raw...)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		if len(keys) != tt.want {
+			t.Errorf("%s: %d keys, want %d", tt.name, len(keys), tt.want)
+		}
+	}
+}
+
+// 配置中的加密值在映射前解密，密钥可来自环境变量或文件
+func TestSecretSettings(t *testing.T) {
+	old, key := testKey(t), testKey(t)
+	sealed, _ := Encrypt(old, "v1")
+	encoded := base64.StdEncoding.EncodeToString(key) + "," + base64.StdEncoding.EncodeToString(old)
+	file := filepath.Join(t.TempDir(), "keys")
+	writeFile(t, file, strings.ReplaceAll(encoded, ",", "\n")+"\n")
+	t.Setenv("BOX_TEST_KEYS", encoded)
+	for _, secret := range []SecretOption{{Env: "BOX_TEST_KEYS"}, {File: file}} {
+		c := &store{}
+		bound := &nameConf{}
+		settings := map[string]interface{}{"name": sealed}
+		if _, err := c.apply(context.Background(), settings, Option{Bind: bound, Secret: secret}); err != nil {
+			t.Fatal(err)
+		}
+		if bound.Name != "v1" {
+			t.Errorf("%+v: name = %q, want v1", secret, bound.Name)
+		}
+	}
+	c := &store{}
+	settings := map[string]interface{}{"name": sealed}
+	if _, err := c.apply(context.Background(), settings, Option{Bind: &nameConf{}, Secret: SecretOption{Env: "BOX_TEST_MISSING"}}); err == nil {
+		t.Error("apply without keys succeeded")
+	}
+}
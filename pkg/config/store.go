@@ -0,0 +1,310 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/aivencs/box/pkg/validate"
+	"github.com/spf13/viper"
+)
+
+// 使用枚举限定变更类型
+type ChangeSupport string
+
+const (
+	ADDED   ChangeSupport = "added"
+	REMOVED ChangeSupport = "removed"
+	CHANGED ChangeSupport = "changed"
+)
+
+// 配置变更项
+type Change struct {
+	Key  string        `json:"key" label:"键名"`
+	Type ChangeSupport `json:"type" label:"变更类型"`
+	Old  interface{}   `json:"old" label:"原值"`
+	New  interface{}   `json:"new" label:"新值"`
+}
+
+// 配置变更回调
+// old与new为映射后的结构体指针，未设置Bind时为nil，均不可修改
+type Callback func(old, new interface{}, diff []Change)
+
+// 已生效的配置
+type snapshot struct {
+	kernel   *viper.Viper
+	flat     map[string]interface{}
+	value    interface{}
+	revision string
+	tag      TagSupport
+	settings map[string]interface{} // 解密与展开引用前的内容
+}
+
+// 各配置对象共用的生效配置存储
+// 每次更新映射到新的结构体后整体替换，读取方不会读到更新一半的值
+type store struct {
+	current   atomic.Value
+	mu        sync.Mutex
+	callbacks []Callback
+	stale     int32
+	pending   string
+	audits    []Audit
+	secrets   map[string]bool
+}
+
+// 注册变更回调
+func (c *store) OnChange(callback Callback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, callback)
+}
+
+// 当前生效的结构体
+func (c *store) Current() interface{} {
+	if s := c.load(); s != nil {
+		return s.value
+	}
+	return nil
+}
+
+func (c *store) load() *snapshot {
+	s, _ := c.current.Load().(*snapshot)
+	return s
+}
+
+// 应用新的配置
+// 生效后写入本地快照，并清除过期标记
+func (c *store) apply(ctx context.Context, settings map[string]interface{}, option Option) ([]Change, error) {
+	diff, err := c.commit(ctx, settings, option)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt32(&c.stale, 0)
+	if utf8.RuneCountInString(option.Snapshot) > 0 {
+		if err := SaveBackup(option, settings); err != nil {
+			report(ctx, "写入配置快照失败", err)
+		}
+	}
+	return diff, nil
+}
+
+// 当前生效的原始配置，供分层配置合并
+func (c *store) settings() map[string]interface{} {
+	if s := c.load(); s != nil {
+		return s.settings
+	}
+	return nil
+}
+
+// 配置是否来自本地快照，远端恢复并更新成功后清除
+func (c *store) Stale() bool {
+	return atomic.LoadInt32(&c.stale) == 1
+}
+
+// 远端不可用时使用本地快照
+// 未配置快照或快照不可用时返回原错误
+func (c *store) fallback(ctx context.Context, kernel *viper.Viper, option Option, cause error) error {
+	if utf8.RuneCountInString(option.Snapshot) == 0 {
+		return cause
+	}
+	backup, err := LoadBackup(option)
+	if err != nil {
+		report(ctx, "读取配置快照失败", err)
+		return cause
+	}
+	if err := kernel.MergeConfigMap(backup.Settings); err != nil {
+		return logger.NewError(logger.EDERROR, "配置快照解析失败", err)
+	}
+	if _, err := c.commit(ctx, backup.Settings, option); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.stale, 1)
+	text := fmt.Sprintf("远端配置不可用，使用%s保存的本地快照", backup.Timestamp.Format(time.RFC3339))
+	report(ctx, text, logger.NewError(logger.RWARN, "", cause))
+	return nil
+}
+
+// 映射并替换生效的配置
+// 回调在释放锁后执行，回调中可读取配置或注册新的回调
+func (c *store) commit(ctx context.Context, settings map[string]interface{}, option Option) ([]Change, error) {
+	diff, notify, err := c.swap(ctx, settings, option)
+	if err != nil {
+		return nil, err
+	}
+	notify()
+	return diff, nil
+}
+
+// 每次映射到同类型的新结构体并整体替换
+// 首次应用时以Bind的副本为基础，通过校验后再写回Bind
+// 返回需在释放锁后执行的回调
+func (c *store) swap(ctx context.Context, settings map[string]interface{}, option Option) ([]Change, func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old := c.load()
+	var target interface{}
+	if option.Bind != nil {
+		target = fresh(option.Bind)
+		if target == nil {
+			return nil, nil, logger.NewError(logger.PVERROR, "用于映射配置的结构体必须为指针", nil)
+		}
+		// 保留Bind中预设的默认值
+		if old == nil {
+			reflect.ValueOf(target).Elem().Set(reflect.ValueOf(option.Bind).Elem())
+		}
+	}
+	// 未通过校验时保留上一次生效的配置，首次应用时Bind保持不变
+	vip, err := bind(ctx, settings, option, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if option.Bind != nil && old == nil {
+		reflect.ValueOf(option.Bind).Elem().Set(reflect.ValueOf(target).Elem())
+		target = option.Bind
+	}
+	next := &snapshot{kernel: vip, flat: flatten("", vip.AllSettings()), value: target, revision: c.pending, tag: option.Tag, settings: settings}
+	var diff []Change
+	if old != nil {
+		diff = compare(old.flat, next.flat)
+	}
+	c.current.Store(next)
+	secrets := secretKeys(settings)
+	notify := func() {}
+	if old != nil && len(diff) > 0 {
+		c.audit(ctx, next.revision, diff, secrets)
+		callbacks := append([]Callback(nil), c.callbacks...)
+		notify = func() {
+			for _, callback := range callbacks {
+				callback(old.value, next.value, diff)
+			}
+		}
+	}
+	c.secrets = secrets
+	return diff, notify, nil
+}
+
+// 应用更新后的配置，失败时记录日志
+func (c *store) update(ctx context.Context, settings map[string]interface{}, option Option) {
+	if _, err := c.apply(ctx, settings, option); err != nil {
+		report(ctx, "配置更新未生效，沿用上一次的配置", err)
+	}
+}
+
+// 记录配置更新时的错误
+// 配置通常先于日志初始化，日志未就绪时使用标准库输出
+func report(ctx context.Context, text string, err error) {
+	ers, ok := err.(*logger.BaseError)
+	if !ok {
+		ers = logger.NewError(logger.RWARN, "", err).(*logger.BaseError)
+	}
+	if !logger.Initialized() {
+		log.Printf("%s: [%d] %s %s", text, ers.Code(), ers.Error(), ers.UnWrapError())
+		return
+	}
+	if _, ok := ctx.Value("trace").(string); !ok {
+		ctx = context.WithValue(ctx, "trace", "config-update")
+	}
+	message := logger.Message{
+		Text:      text,
+		Remark:    ers.Error(),
+		Traceback: ers.UnWrapError(),
+		Attr: logger.Attr{
+			Monitor: logger.Monitor{
+				Code:  ers.Code(),
+				Level: ers.Level(),
+			},
+		},
+	}
+	if ers.Level() == logger.WARN {
+		logger.Warn(ctx, message)
+		return
+	}
+	logger.Error(ctx, message)
+}
+
+// 解密并展开引用后映射到target，target为结构体时校验
+// target为nil时仅解析
+func bind(ctx context.Context, settings map[string]interface{}, option Option, target interface{}) (*viper.Viper, error) {
+	// 解密形如ENC(base64)的值
+	if hasSecret(settings) {
+		keys, err := LoadKeys(option.Secret)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := decryptSettings(settings, keys)
+		if err != nil {
+			return nil, err
+		}
+		settings = plain.(map[string]interface{})
+	}
+	// 展开对环境变量、其他配置项与文件的引用
+	settings, err := interpolate(settings)
+	if err != nil {
+		return nil, err
+	}
+	vip := viper.New()
+	if err := vip.MergeConfigMap(settings); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置解析失败", err)
+	}
+	if target == nil {
+		return vip, nil
+	}
+	if err := vip.Unmarshal(target, withTag(option.Tag)); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置映射失败", err)
+	}
+	if isStruct(target) {
+		message, err := validate.Work(ctx, target)
+		if err != nil {
+			return nil, logger.NewError(logger.DVERROR, message, err)
+		}
+	}
+	return vip, nil
+}
+
+// 创建与Bind同类型的新对象，Bind不是指针时返回nil
+func fresh(v interface{}) interface{} {
+	kind := reflect.TypeOf(v)
+	if kind.Kind() != reflect.Ptr {
+		return nil
+	}
+	return reflect.New(kind.Elem()).Interface()
+}
+
+func isStruct(v interface{}) bool {
+	kind := reflect.TypeOf(v)
+	for kind.Kind() == reflect.Ptr {
+		kind = kind.Elem()
+	}
+	return kind.Kind() == reflect.Struct
+}
+
+// 比较展开后的配置，结果按键名排序
+func compare(old, new map[string]interface{}) []Change {
+	diff := []Change{}
+	for key, val := range new {
+		prev, ok := old[key]
+		if !ok {
+			diff = append(diff, Change{Key: key, Type: ADDED, New: val})
+			continue
+		}
+		if !reflect.DeepEqual(prev, val) {
+			diff = append(diff, Change{Key: key, Type: CHANGED, Old: prev, New: val})
+		}
+	}
+	for key, val := range old {
+		if _, ok := new[key]; !ok {
+			diff = append(diff, Change{Key: key, Type: REMOVED, Old: val})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Key < diff[j].Key
+	})
+	return diff
+}
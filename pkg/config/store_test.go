@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 回调中读取配置或注册回调时不应死锁
+func TestCallbackMayUseStore(t *testing.T) {
+	ctx := context.Background()
+	c := &store{}
+	if _, err := c.apply(ctx, map[string]interface{}{"name": "v1"}, Option{}); err != nil {
+		t.Fatal(err)
+	}
+	var diff []Change
+	c.OnChange(func(old, new interface{}, changes []Change) {
+		diff = changes
+		c.OnChange(func(old, new interface{}, changes []Change) {})
+		c.Audits()
+		c.GetString("name")
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := c.apply(ctx, map[string]interface{}{"name": "v2"}, Option{}); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("callback deadlocked")
+	}
+	if len(diff) != 1 || diff[0].Key != "name" || diff[0].New != "v2" {
+		t.Errorf("diff = %v", diff)
+	}
+}
+
+// 默认按mapstructure映射，设置Tag为json时按json标签映射
+func TestBindTag(t *testing.T) {
+	type target struct {
+		PoolCap int `json:"pool_cap"`
+	}
+	settings := map[string]interface{}{"pool_cap": 8, "poolcap": 4}
+	tests := []struct {
+		tag  TagSupport
+		want int
+	}{
+		{tag: "", want: 4},
+		{tag: TAG_MAPSTRUCTURE, want: 4},
+		{tag: TAG_JSON, want: 8},
+	}
+	for _, tt := range tests {
+		c := &store{}
+		bound := &target{}
+		if _, err := c.apply(context.Background(), settings, Option{Bind: bound, Tag: tt.tag}); err != nil {
+			t.Fatal(err)
+		}
+		if bound.PoolCap != tt.want {
+			t.Errorf("tag %q: PoolCap = %d, want %d", tt.tag, bound.PoolCap, tt.want)
+		}
+	}
+}
+
+// 首次应用未通过校验时不修改Bind，通过后保留其中预设的默认值
+func TestFirstApplyKeepsBindOnError(t *testing.T) {
+	type target struct {
+		Name string `json:"name" validate:"required"`
+		Port int    `json:"port" validate:"max=10"`
+	}
+	ctx := context.Background()
+	c := &store{}
+	bound := &target{Name: "default"}
+	option := Option{Bind: bound}
+	if _, err := c.apply(ctx, map[string]interface{}{"name": "changed", "port": 99}, option); err == nil {
+		t.Fatal("apply with invalid port succeeded")
+	}
+	if *bound != (target{Name: "default"}) {
+		t.Fatalf("Bind = %+v after failed apply", *bound)
+	}
+	if c.Current() != nil {
+		t.Fatalf("Current = %+v after failed apply, want nil", c.Current())
+	}
+	if _, err := c.apply(ctx, map[string]interface{}{"port": 5}, option); err != nil {
+		t.Fatal(err)
+	}
+	if *bound != (target{Name: "default", Port: 5}) {
+		t.Errorf("Bind = %+v, want default name and port 5", *bound)
+	}
+	if c.Current() != bound {
+		t.Error("Current is not Bind after first apply")
+	}
+	// 此后的更新映射到新的结构体，Bind保持首次生效的值
+	if _, err := c.apply(ctx, map[string]interface{}{"name": "v2", "port": 6}, option); err != nil {
+		t.Fatal(err)
+	}
+	if bound.Port != 5 || c.Current().(*target).Port != 6 {
+		t.Errorf("Bind port = %d, Current port = %d, want 5 and 6", bound.Port, c.Current().(*target).Port)
+	}
+}
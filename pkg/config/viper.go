@@ -2,18 +2,21 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 	"unicode/utf8"
 
 	"github.com/aivencs/box/pkg/logger"
 	"github.com/aivencs/box/pkg/validate"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
-	_ "github.com/spf13/viper/remote"
 )
 
 // 使用枚举限定使用时的选项
@@ -21,11 +24,25 @@ type TypeSupport string
 
 const (
 	Consul TypeSupport = "consul"
+	FILE   TypeSupport = "file"
+	ETCD   TypeSupport = "etcd"
+)
+
+// 映射结构体时使用的标签
+type TagSupport string
+
+const (
+	TAG_MAPSTRUCTURE TagSupport = "mapstructure"
+	TAG_JSON         TagSupport = "json"
 )
 
 const (
-	DEFAULT_WATCH_INTERVAL = 180                     // 默认的更新检查间隔
-	DEFAULT_HOST_CONSUL    = "http://localhost:8500" // Consul 服务默认地址
+	DEFAULT_WATCH_INTERVAL  = 180                     // 默认的更新检查间隔
+	DEFAULT_HOST_CONSUL     = "http://localhost:8500" // Consul 服务默认地址
+	DEFAULT_HOST_ETCD       = "localhost:2379"        // Etcd 服务默认地址
+	DEFAULT_CONSUL_MIN_WAIT = time.Second             // Consul 两次查询的最小间隔
+	DEFAULT_BACKOFF_MIN     = time.Second             // 出错后的最短重试间隔
+	DEFAULT_BACKOFF_MAX     = time.Minute             // 出错后的最长重试间隔
 )
 
 // 定义对象
@@ -39,28 +56,46 @@ func init() {
 
 // 抽象接口
 type Conf interface {
-	PeriodicUpdate(ctx context.Context, option Option) // 定期更新
+	Reader
+	PeriodicUpdate(ctx context.Context, option Option) // 定期更新，ctx结束时退出
+	OnChange(callback Callback)                        // 注册变更回调
+	Current() interface{}                              // 当前生效的结构体
+	Stale() bool                                       // 是否使用本地快照
+	SourceRevision() string                            // 当前生效配置的来源版本
+	Audits() []Audit                                   // 变更记录
 }
 
 // 配置初始化时所用参数
 type Option struct {
-	Auth        bool        `json:"auth" label:"是否鉴权" desc:"鉴权时启用Username和Password"`
-	Host        string      `json:"host" label:"路径" desc:"文件则填写文件路径"`
-	Application string      `json:"application" label:"应用名称" desc:"必须与远端配置名称相同" validate:"required"`
-	Env         string      `json:"env" label:"环境" desc:"推荐不同环境不同配置" validate:"required"`
-	Type        string      `json:"type" label:"类型" desc:"用于指定配置格式类型，例如yaml/json" validate:"required"`
-	Bind        interface{} `json:"bind" label:"用于映射配置的结构体" desc:"远端配置会被映射到结构体" validate:"required"`
-	Username    string      `json:"username" label:"用户名" desc:"需要鉴权时使用"`
-	Password    string      `json:"password" label:"密码" desc:"需要鉴权时使用"`
-	Update      bool        `json:"update" label:"是否自动更新配置" desc:"默认不自动更新"`
-	Interval    int         `json:"interval" label:"即时更新检查间隔" desc:"默认三分钟"`
+	Auth        bool         `json:"auth" label:"是否鉴权" desc:"鉴权时启用Username和Password"`
+	Host        string       `json:"host" label:"路径" desc:"文件则填写文件路径"`
+	Application string       `json:"application" label:"应用名称" desc:"必须与远端配置名称相同" validate:"required"`
+	Env         string       `json:"env" label:"环境" desc:"推荐不同环境不同配置" validate:"required"`
+	Type        string       `json:"type" label:"类型" desc:"用于指定配置格式类型，例如yaml/json" validate:"required"`
+	Bind        interface{}  `json:"bind" label:"用于映射配置的结构体" desc:"须为指针，首次读取时映射至此，更新后的值通过Current获取" validate:"required"`
+	Username    string       `json:"username" label:"用户名" desc:"需要鉴权时使用"`
+	Password    string       `json:"password" label:"密码" desc:"需要鉴权时使用"`
+	Update      bool         `json:"update" label:"是否自动更新配置" desc:"默认不自动更新"`
+	Interval    int          `json:"interval" label:"即时更新检查间隔" desc:"默认三分钟" default:"180"`
+	CAFile      string       `json:"ca_file" label:"CA证书路径" desc:"填写时启用TLS，用于etcd与consul"`
+	CertFile    string       `json:"cert_file" label:"客户端证书路径" desc:"双向认证时使用"`
+	KeyFile     string       `json:"key_file" label:"客户端私钥路径" desc:"双向认证时使用"`
+	Layer       LayerOption  `json:"layer" label:"分层参数" desc:"用于分层配置"`
+	Secret      SecretOption `json:"secret" label:"密钥参数" desc:"配置中包含ENC(base64)格式的加密值时使用"`
+	Snapshot    string       `json:"snapshot" label:"本地快照路径" desc:"填写时每次加载成功后写入快照，远端不可用时据此启动"`
+	Tag         TagSupport   `json:"tag" label:"映射标签" desc:"默认为mapstructure，设为json时按json标签映射" validate:"omitempty,oneof=mapstructure json"`
 }
 
 // 初始化配置对象
 func InitConf(ctx context.Context, support TypeSupport, option Option) error {
 	c := conf
 	var err error
-	message, err := validate.Work(ctx, option)
+	// Bind的内容在读取配置后校验，此处仅校验是否设置
+	probe := option
+	if probe.Bind != nil {
+		probe.Bind = &struct{}{}
+	}
+	message, err := validate.Work(ctx, probe)
 	if err != nil {
 		return logger.NewError(logger.PVERROR, message, err)
 	}
@@ -71,8 +106,12 @@ func InitConf(ctx context.Context, support TypeSupport, option Option) error {
 		}
 		if c == nil {
 			err = errors.New("初始化失败")
+			return
 		}
 		conf = c
+		if option.Update {
+			go c.PeriodicUpdate(ctx, option)
+		}
 	})
 	return err
 }
@@ -82,6 +121,12 @@ func ConfFactory(ctx context.Context, support TypeSupport, option Option) (Conf,
 	switch support {
 	case Consul:
 		return NewConsulConf(ctx, option)
+	case FILE:
+		return NewFileConf(ctx, option)
+	case ETCD:
+		return NewEtcdConf(ctx, option)
+	case LAYERED:
+		return NewLayeredConf(ctx, option)
 	default:
 		return NewConsulConf(ctx, option)
 	}
@@ -90,7 +135,12 @@ func ConfFactory(ctx context.Context, support TypeSupport, option Option) (Conf,
 // Conf 结构体
 // 基于 Consul
 type ConsulConf struct {
+	store
 	Kernel *viper.Viper
+	Client *api.Client
+	Key    string
+	Index  uint64
+	option Option
 }
 
 // 创建基于Consul的配置对象
@@ -99,41 +149,176 @@ func NewConsulConf(ctx context.Context, option Option) (Conf, error) {
 	if utf8.RuneCountInString(option.Host) == 0 {
 		option.Host = DEFAULT_HOST_CONSUL
 	}
+	cfg := api.DefaultConfig()
+	cfg.Address = option.Host
 	if option.Auth {
-		os.Setenv("CONSUL_HTTP_AUTH", fmt.Sprintf("%s:%s", option.Username, option.Password))
+		cfg.HttpAuth = &api.HttpBasicAuth{Username: option.Username, Password: option.Password}
+	}
+	if utf8.RuneCountInString(option.CAFile) > 0 {
+		cfg.TLSConfig = api.TLSConfig{
+			CAFile:   option.CAFile,
+			CertFile: option.CertFile,
+			KeyFile:  option.KeyFile,
+		}
+	}
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, logger.NewError(logger.PVERROR, "Consul参数有误", err)
 	}
 	// 开始构建
 	vip := viper.New()
-	name := fmt.Sprintf("%s/%s", option.Application, option.Env)
 	vip.SetConfigType(option.Type)
-	vip.AddRemoteProvider("consul", option.Host, name)
-	// 获取远端配置并映射到结构体
-	err := vip.ReadRemoteConfig()
+	c := &ConsulConf{
+		Kernel: vip,
+		Client: client,
+		Key:    consulKey(option.Application, option.Env),
+		option: option,
+	}
+	// 获取远端配置并映射到结构体，无法连接时使用本地快照
+	settings, err := c.reload(ctx)
 	if err != nil {
+		if !unreachable(err) {
+			return nil, err
+		}
+		if err := c.fallback(ctx, c.Kernel, option, err); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if _, err := c.apply(ctx, settings, option); err != nil {
 		return nil, err
 	}
-	if option.Bind != nil {
-		vip.Unmarshal(&option.Bind)
+	return c, nil
+}
+
+// 配置在Consul中的键名
+func consulKey(app string, env string) string {
+	return fmt.Sprintf("%s/%s", app, env)
+}
+
+// 使用指定的标签映射结构体，未指定时沿用mapstructure
+func withTag(tag TagSupport) viper.DecoderConfigOption {
+	return func(c *mapstructure.DecoderConfig) {
+		if tag != "" {
+			c.TagName = string(tag)
+		}
 	}
-	return &ConsulConf{Kernel: vip}, nil
 }
 
-// 定期更新
+// 通过阻塞查询监听变化
+// 以X-Consul-Index长轮询，配置变化后立即返回，Interval为单次最长等待秒数
 func (c *ConsulConf) PeriodicUpdate(ctx context.Context, option Option) {
 	if option.Interval == 0 {
 		option.Interval = DEFAULT_WATCH_INTERVAL
 	}
-	ticker := time.NewTicker(time.Second * time.Duration(option.Interval))
-	defer ticker.Stop()
+	wait := time.Second * time.Duration(option.Interval)
+	backoff := time.Duration(0)
 	for {
-		select {
-		case <-ticker.C:
-			c.Kernel.WatchRemoteConfig()
-			if option.Bind != nil {
-				c.Kernel.Unmarshal(&option.Bind)
+		last := time.Now()
+		query := &api.QueryOptions{WaitIndex: c.Index, WaitTime: wait}
+		pair, meta, err := c.Client.KV().Get(c.Key, query.WithContext(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// 出错时指数退避并加入随机抖动，避免同时重试
+			backoff = next(backoff)
+			report(ctx, "监听配置失败", logger.NewError(logger.CALLERROR, "", err))
+			if !sleep(ctx, backoff) {
+				return
 			}
-		default:
 			continue
 		}
+		backoff = 0
+		switch {
+		case meta.LastIndex < c.Index:
+			// 索引回退时重新开始
+			c.Index = 0
+		case meta.LastIndex == c.Index:
+			// 等待超时，未发生变化
+		default:
+			c.Index = meta.LastIndex
+			c.track(strconv.FormatUint(c.Index, 10))
+			settings, err := c.parse(pair)
+			if err != nil {
+				report(ctx, "读取配置失败", err)
+				break
+			}
+			c.update(ctx, settings, option)
+		}
+		// 两次查询之间保持最小间隔，避免频繁变化时压垮服务端
+		if !sleep(ctx, DEFAULT_CONSUL_MIN_WAIT-time.Since(last)) {
+			return
+		}
+	}
+}
+
+// 重新读取远端配置
+func (c *ConsulConf) reload(ctx context.Context) (map[string]interface{}, error) {
+	pair, meta, err := c.Client.KV().Get(c.Key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, logger.NewError(logger.CALLERROR, "读取Consul失败", err)
+	}
+	c.Index = meta.LastIndex
+	c.track(strconv.FormatUint(c.Index, 10))
+	return c.parse(pair)
+}
+
+func (c *ConsulConf) parse(pair *api.KVPair) (map[string]interface{}, error) {
+	if pair == nil {
+		return nil, logger.NewError(logger.CHECK, "配置不存在", nil)
+	}
+	if err := c.Kernel.ReadConfig(bytes.NewReader(pair.Value)); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置解析失败", err)
+	}
+	return c.Kernel.AllSettings(), nil
+}
+
+// 计算下一次退避时间
+func next(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff < DEFAULT_BACKOFF_MIN {
+		backoff = DEFAULT_BACKOFF_MIN
 	}
+	if backoff > DEFAULT_BACKOFF_MAX {
+		backoff = DEFAULT_BACKOFF_MAX
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// 等待指定时间，ctx结束时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// 注册变更回调
+func OnChange(callback Callback) error {
+	if conf == nil {
+		return logger.NewError(logger.RPERROR, "配置未初始化", nil)
+	}
+	conf.OnChange(callback)
+	return nil
+}
+
+// 当前生效的结构体，未初始化时为nil
+func Current() interface{} {
+	if conf == nil {
+		return nil
+	}
+	return conf.Current()
+}
+
+// 是否使用本地快照，可用于健康检查
+func Stale() bool {
+	return conf != nil && conf.Stale()
 }
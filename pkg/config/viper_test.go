@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Consul单次响应，status非200时返回错误
+type consulReply struct {
+	status int
+	index  uint64
+	value  string
+}
+
+// 按顺序返回预设的响应，用尽后阻塞至请求结束
+// 设置kv时改为键值存储，支持读取与check-and-set事务
+type fakeConsul struct {
+	mu        sync.Mutex
+	replies   []consulReply
+	indexes   []string
+	times     []time.Time
+	stop      chan struct{}
+	kv        map[string]*api.KVPair
+	index     uint64
+	beforeTxn func() // 执行下一次事务前调用一次，用于模拟并发修改
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.kv != nil {
+		f.serveKV(w, r)
+		return
+	}
+	f.mu.Lock()
+	f.indexes = append(f.indexes, r.URL.Query().Get("index"))
+	f.times = append(f.times, time.Now())
+	if len(f.replies) == 0 {
+		f.mu.Unlock()
+		select {
+		case <-r.Context().Done():
+		case <-f.stop:
+		}
+		return
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	f.mu.Unlock()
+	if reply.status != http.StatusOK {
+		w.WriteHeader(reply.status)
+		return
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(reply.index, 10))
+	json.NewEncoder(w).Encode([]map[string]interface{}{
+		{"Key": "app/dev", "Value": []byte(reply.value), "ModifyIndex": reply.index},
+	})
+}
+
+// 写入键值，修改索引递增
+func (f *fakeConsul) put(key string, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.write(key, []byte(value))
+}
+
+func (f *fakeConsul) write(key string, value []byte) {
+	f.index++
+	f.kv[key] = &api.KVPair{Key: key, Value: value, ModifyIndex: f.index}
+}
+
+func (f *fakeConsul) get(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if pair, ok := f.kv[key]; ok {
+		return string(pair.Value)
+	}
+	return ""
+}
+
+func (f *fakeConsul) serveKV(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut && r.URL.Path == "/v1/txn" {
+		f.serveTxn(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	pair, ok := f.kv[strings.TrimPrefix(r.URL.Path, "/v1/kv/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode([]*api.KVPair{pair})
+}
+
+// 全部操作的索引与当前一致时才写入，索引为0表示键不存在
+func (f *fakeConsul) serveTxn(w http.ResponseWriter, r *http.Request) {
+	var ops api.TxnOps
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	before := f.beforeTxn
+	f.beforeTxn = nil
+	f.mu.Unlock()
+	if before != nil {
+		before()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	res := api.TxnResponse{}
+	for i, op := range ops {
+		var modify uint64
+		if pair, ok := f.kv[op.KV.Key]; ok {
+			modify = pair.ModifyIndex
+		}
+		if op.KV.Verb != api.KVCAS || op.KV.Index != modify {
+			res.Errors = append(res.Errors, &api.TxnError{OpIndex: i, What: "failed to set key " + op.KV.Key})
+		}
+	}
+	if len(res.Errors) > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	for _, op := range ops {
+		f.write(op.KV.Key, op.KV.Value)
+		res.Results = append(res.Results, &api.TxnResult{KV: f.kv[op.KV.Key]})
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeConsul) requests() ([]string, []time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.indexes...), append([]time.Time(nil), f.times...)
+}
+
+func TestConsulBlockingQuery(t *testing.T) {
+	f := &fakeConsul{stop: make(chan struct{}), replies: []consulReply{
+		{status: http.StatusOK, index: 10, value: "name: v1"},
+		// 索引前进，应用新配置
+		{status: http.StatusOK, index: 12, value: "name: v2"},
+		// 出错后退避，沿用原索引重试
+		{status: http.StatusInternalServerError},
+		// 索引回退，不带索引重新查询
+		{status: http.StatusOK, index: 5, value: "name: v2"},
+		{status: http.StatusOK, index: 6, value: "name: v3"},
+	}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	// 先结束阻塞中的查询，避免关闭服务时等待
+	defer close(f.stop)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	option := Option{Host: srv.URL, Application: "app", Env: "dev", Type: "yaml", Bind: &nameConf{}, Interval: 1}
+	c, err := NewConsulConf(ctx, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitName(t, c, "v1")
+	go c.PeriodicUpdate(ctx, option)
+	waitName(t, c, "v2")
+	waitName(t, c, "v3")
+	if got := c.SourceRevision(); got != "6" {
+		t.Errorf("SourceRevision = %q, want 6", got)
+	}
+	// 等待下一次阻塞查询发出
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if indexes, _ := f.requests(); len(indexes) >= 6 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	indexes, times := f.requests()
+	want := []string{"", "10", "12", "12", "", "6"}
+	if len(indexes) != len(want) {
+		t.Fatalf("indexes = %v, want %v", indexes, want)
+	}
+	for i := range want {
+		if indexes[i] != want[i] {
+			t.Fatalf("indexes = %v, want %v", indexes, want)
+		}
+	}
+	// 退避时间加入抖动，不少于最短退避的一半
+	if gap := times[3].Sub(times[2]); gap < DEFAULT_BACKOFF_MIN/2 {
+		t.Errorf("retried after %s, want backoff of at least %s", gap, DEFAULT_BACKOFF_MIN/2)
+	}
+}
@@ -0,0 +1,428 @@
+package filter
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	redisbloom "github.com/RedisBloom/redisbloom-go"
+	"github.com/aivencs/box/pkg/logger"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// 基数估计
+type HyperLogLog interface {
+	Add(ctx context.Context, key string, vals ...string) (bool, error)
+	Count(ctx context.Context, keys ...string) (int64, error)
+	Merge(ctx context.Context, dest string, srcs ...string) error
+}
+
+// 频率估计
+type CountMinSketch interface {
+	InitByDim(ctx context.Context, key string, width int64, depth int64) error
+	InitByProb(ctx context.Context, key string, errorRate float64, probability float64) error
+	IncrBy(ctx context.Context, key string, item string, increment int64) (int64, error)
+	Query(ctx context.Context, key string, items ...string) ([]int64, error)
+	Merge(ctx context.Context, dest string, srcs []string, weights []int64) error
+}
+
+// 高频项统计
+type TopK interface {
+	Reserve(ctx context.Context, key string, k int64, width int64, depth int64, decay float64) error
+	Add(ctx context.Context, key string, items ...string) ([]string, error)
+	IncrBy(ctx context.Context, key string, item string, increment int64) (string, error)
+	Query(ctx context.Context, key string, items ...string) ([]bool, error)
+	Count(ctx context.Context, key string, items ...string) ([]int64, error)
+	List(ctx context.Context, key string) ([]string, error)
+}
+
+// 获取基于InitFilter连接的基数估计对象
+func GetHyperLogLog() (HyperLogLog, error) {
+	c, ok := filter.(*BloomFilter)
+	if !ok {
+		return nil, logger.NewError(logger.RPERROR, "过滤器未初始化", nil)
+	}
+	return c.HyperLogLog(), nil
+}
+
+// 获取基于InitFilter连接的频率估计对象
+func GetCountMinSketch() (CountMinSketch, error) {
+	c, ok := filter.(*BloomFilter)
+	if !ok {
+		return nil, logger.NewError(logger.RPERROR, "过滤器未初始化", nil)
+	}
+	return c.CountMinSketch(), nil
+}
+
+// 获取基于InitFilter连接的高频项统计对象
+func GetTopK() (TopK, error) {
+	c, ok := filter.(*BloomFilter)
+	if !ok {
+		return nil, logger.NewError(logger.RPERROR, "过滤器未初始化", nil)
+	}
+	return c.TopK(), nil
+}
+
+// 共用连接池的基数估计对象
+func (c *BloomFilter) HyperLogLog() HyperLogLog {
+	return &RedisHyperLogLog{Pool: c.Pool}
+}
+
+// 共用客户端的频率估计对象
+func (c *BloomFilter) CountMinSketch() CountMinSketch {
+	return &RedisCountMinSketch{Kernel: c.Kernel}
+}
+
+// 共用客户端的高频项统计对象
+func (c *BloomFilter) TopK() TopK {
+	return &RedisTopK{Kernel: c.Kernel}
+}
+
+// 结构体
+// 基于Redis的PF命令
+type RedisHyperLogLog struct {
+	Pool *redigo.Pool
+}
+
+func (c *RedisHyperLogLog) Add(ctx context.Context, key string, vals ...string) (bool, error) {
+	r := c.Pool.Get()
+	defer r.Close()
+	return redigo.Bool(r.Do("PFADD", redigo.Args{key}.AddFlat(vals)...))
+}
+
+func (c *RedisHyperLogLog) Count(ctx context.Context, keys ...string) (int64, error) {
+	r := c.Pool.Get()
+	defer r.Close()
+	return redigo.Int64(r.Do("PFCOUNT", redigo.Args{}.AddFlat(keys)...))
+}
+
+func (c *RedisHyperLogLog) Merge(ctx context.Context, dest string, srcs ...string) error {
+	r := c.Pool.Get()
+	defer r.Close()
+	_, err := r.Do("PFMERGE", redigo.Args{dest}.AddFlat(srcs)...)
+	return err
+}
+
+// 结构体
+// 基于RedisBloom的CMS命令
+type RedisCountMinSketch struct {
+	Kernel *redisbloom.Client
+}
+
+func (c *RedisCountMinSketch) InitByDim(ctx context.Context, key string, width int64, depth int64) error {
+	_, err := c.Kernel.CmsInitByDim(key, width, depth)
+	return err
+}
+
+func (c *RedisCountMinSketch) InitByProb(ctx context.Context, key string, errorRate float64, probability float64) error {
+	_, err := c.Kernel.CmsInitByProb(key, errorRate, probability)
+	return err
+}
+
+func (c *RedisCountMinSketch) IncrBy(ctx context.Context, key string, item string, increment int64) (int64, error) {
+	res, err := c.Kernel.CmsIncrBy(key, map[string]int64{item: increment})
+	if err != nil || len(res) == 0 {
+		return 0, err
+	}
+	return res[0], nil
+}
+
+func (c *RedisCountMinSketch) Query(ctx context.Context, key string, items ...string) ([]int64, error) {
+	return c.Kernel.CmsQuery(key, items)
+}
+
+func (c *RedisCountMinSketch) Merge(ctx context.Context, dest string, srcs []string, weights []int64) error {
+	_, err := c.Kernel.CmsMerge(dest, srcs, weights)
+	return err
+}
+
+// 结构体
+// 基于RedisBloom的TOPK命令
+type RedisTopK struct {
+	Kernel *redisbloom.Client
+}
+
+func (c *RedisTopK) Reserve(ctx context.Context, key string, k int64, width int64, depth int64, decay float64) error {
+	_, err := c.Kernel.TopkReserve(key, k, width, depth, decay)
+	return err
+}
+
+func (c *RedisTopK) Add(ctx context.Context, key string, items ...string) ([]string, error) {
+	return c.Kernel.TopkAdd(key, items)
+}
+
+func (c *RedisTopK) IncrBy(ctx context.Context, key string, item string, increment int64) (string, error) {
+	res, err := c.Kernel.TopkIncrBy(key, map[string]int64{item: increment})
+	if err != nil || len(res) == 0 {
+		return "", err
+	}
+	return res[0], nil
+}
+
+func (c *RedisTopK) Query(ctx context.Context, key string, items ...string) ([]bool, error) {
+	res, err := c.Kernel.TopkQuery(key, items)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(res))
+	for i, v := range res {
+		result[i] = v == 1
+	}
+	return result, nil
+}
+
+func (c *RedisTopK) Count(ctx context.Context, key string, items ...string) ([]int64, error) {
+	return c.Kernel.TopkCount(key, items)
+}
+
+func (c *RedisTopK) List(ctx context.Context, key string) ([]string, error) {
+	return c.Kernel.TopkList(key)
+}
+
+// 结构体
+// 基于内存，结果为精确值，用于测试
+type MemoryHyperLogLog struct {
+	mu   sync.Mutex
+	sets map[string]map[string]struct{}
+}
+
+func NewMemoryHyperLogLog() *MemoryHyperLogLog {
+	return &MemoryHyperLogLog{sets: map[string]map[string]struct{}{}}
+}
+
+func (c *MemoryHyperLogLog) Add(ctx context.Context, key string, vals ...string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	set, ok := c.sets[key]
+	if !ok {
+		set = map[string]struct{}{}
+		c.sets[key] = set
+	}
+	changed := !ok
+	for _, v := range vals {
+		if _, exist := set[v]; !exist {
+			set[v] = struct{}{}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func (c *MemoryHyperLogLog) Count(ctx context.Context, keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(len(c.union(keys))), nil
+}
+
+func (c *MemoryHyperLogLog) Merge(ctx context.Context, dest string, srcs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets[dest] = c.union(append([]string{dest}, srcs...))
+	return nil
+}
+
+func (c *MemoryHyperLogLog) union(keys []string) map[string]struct{} {
+	result := map[string]struct{}{}
+	for _, key := range keys {
+		for v := range c.sets[key] {
+			result[v] = struct{}{}
+		}
+	}
+	return result
+}
+
+// 结构体
+// 基于内存，结果为精确值，用于测试
+type MemoryCountMinSketch struct {
+	mu       sync.Mutex
+	counters map[string]map[string]int64
+}
+
+func NewMemoryCountMinSketch() *MemoryCountMinSketch {
+	return &MemoryCountMinSketch{counters: map[string]map[string]int64{}}
+}
+
+func (c *MemoryCountMinSketch) InitByDim(ctx context.Context, key string, width int64, depth int64) error {
+	return c.init(key)
+}
+
+func (c *MemoryCountMinSketch) InitByProb(ctx context.Context, key string, errorRate float64, probability float64) error {
+	return c.init(key)
+}
+
+func (c *MemoryCountMinSketch) init(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counters[key]; ok {
+		return logger.NewError(logger.RPERROR, "键已存在", nil)
+	}
+	c.counters[key] = map[string]int64{}
+	return nil
+}
+
+func (c *MemoryCountMinSketch) IncrBy(ctx context.Context, key string, item string, increment int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.counters[key]
+	if !ok {
+		return 0, logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	counter[item] += increment
+	return counter[item], nil
+}
+
+func (c *MemoryCountMinSketch) Query(ctx context.Context, key string, items ...string) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.counters[key]
+	if !ok {
+		return nil, logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	result := make([]int64, len(items))
+	for i, item := range items {
+		result[i] = counter[item]
+	}
+	return result, nil
+}
+
+func (c *MemoryCountMinSketch) Merge(ctx context.Context, dest string, srcs []string, weights []int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counters[dest]; !ok {
+		return logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	if len(weights) > 0 && len(weights) != len(srcs) {
+		return logger.NewError(logger.RPERROR, "权重数量与来源数量不一致", nil)
+	}
+	merged := map[string]int64{}
+	for i, src := range srcs {
+		counter, ok := c.counters[src]
+		if !ok {
+			return logger.NewError(logger.RPERROR, "键不存在", nil)
+		}
+		weight := int64(1)
+		if len(weights) > 0 {
+			weight = weights[i]
+		}
+		for item, count := range counter {
+			merged[item] += count * weight
+		}
+	}
+	c.counters[dest] = merged
+	return nil
+}
+
+// 结构体
+// 基于内存，结果为精确值，用于测试
+type MemoryTopK struct {
+	mu    sync.Mutex
+	sizes map[string]int64
+	items map[string]map[string]int64
+}
+
+func NewMemoryTopK() *MemoryTopK {
+	return &MemoryTopK{sizes: map[string]int64{}, items: map[string]map[string]int64{}}
+}
+
+func (c *MemoryTopK) Reserve(ctx context.Context, key string, k int64, width int64, depth int64, decay float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; ok {
+		return logger.NewError(logger.RPERROR, "键已存在", nil)
+	}
+	c.sizes[key] = k
+	c.items[key] = map[string]int64{}
+	return nil
+}
+
+func (c *MemoryTopK) Add(ctx context.Context, key string, items ...string) ([]string, error) {
+	result := make([]string, len(items))
+	for i, item := range items {
+		expelled, err := c.IncrBy(ctx, key, item, 1)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = expelled
+	}
+	return result, nil
+}
+
+func (c *MemoryTopK) IncrBy(ctx context.Context, key string, item string, increment int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.items[key]
+	if !ok {
+		return "", logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	before := c.top(key)
+	counter[item] += increment
+	after := map[string]bool{}
+	for _, v := range c.top(key) {
+		after[v] = true
+	}
+	for _, v := range before {
+		if !after[v] {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+func (c *MemoryTopK) Query(ctx context.Context, key string, items ...string) ([]bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		return nil, logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	top := map[string]bool{}
+	for _, v := range c.top(key) {
+		top[v] = true
+	}
+	result := make([]bool, len(items))
+	for i, item := range items {
+		result[i] = top[item]
+	}
+	return result, nil
+}
+
+func (c *MemoryTopK) Count(ctx context.Context, key string, items ...string) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.items[key]
+	if !ok {
+		return nil, logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	result := make([]int64, len(items))
+	for i, item := range items {
+		result[i] = counter[item]
+	}
+	return result, nil
+}
+
+func (c *MemoryTopK) List(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		return nil, logger.NewError(logger.RPERROR, "键不存在", nil)
+	}
+	return c.top(key), nil
+}
+
+// 按计数降序取前k项，计数相同时按名称排序
+func (c *MemoryTopK) top(key string) []string {
+	counter := c.items[key]
+	result := make([]string, 0, len(counter))
+	for item := range counter {
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if counter[result[i]] != counter[result[j]] {
+			return counter[result[i]] > counter[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	if int64(len(result)) > c.sizes[key] {
+		result = result[:c.sizes[key]]
+	}
+	return result
+}
@@ -0,0 +1,239 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	redisbloom "github.com/RedisBloom/redisbloom-go"
+)
+
+// 设置BOX_TEST_REDIS为RedisBloom地址时同时测试Redis实现
+func counterBackends() map[string]func() (CountMinSketch, TopK) {
+	backends := map[string]func() (CountMinSketch, TopK){
+		"memory": func() (CountMinSketch, TopK) {
+			return NewMemoryCountMinSketch(), NewMemoryTopK()
+		},
+	}
+	if addr := os.Getenv("BOX_TEST_REDIS"); addr != "" {
+		backends["redis"] = func() (CountMinSketch, TopK) {
+			kernel := redisbloom.NewClient(addr, "box-test", nil)
+			return &RedisCountMinSketch{Kernel: kernel}, &RedisTopK{Kernel: kernel}
+		}
+	}
+	return backends
+}
+
+// 每个用例使用独立的键名，避免Redis中残留的数据影响结果
+func testKey(t *testing.T, name string) string {
+	return fmt.Sprintf("box-test:%s:%s:%d", t.Name(), name, time.Now().UnixNano())
+}
+
+func TestCountMinSketch(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		incrs map[string]int64
+		query []string
+		want  []int64
+	}{
+		{name: "single", incrs: map[string]int64{"a": 3}, query: []string{"a"}, want: []int64{3}},
+		{name: "multiple", incrs: map[string]int64{"a": 4, "b": 2}, query: []string{"a", "b"}, want: []int64{4, 2}},
+		{name: "missing item", incrs: map[string]int64{"a": 1}, query: []string{"z"}, want: []int64{0}},
+	}
+	for backend, build := range counterBackends() {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				cms, _ := build()
+				key := testKey(t, "cms")
+				if err := cms.InitByDim(ctx, key, 2000, 5); err != nil {
+					t.Fatalf("InitByDim: %v", err)
+				}
+				for item, n := range tt.incrs {
+					if _, err := cms.IncrBy(ctx, key, item, n); err != nil {
+						t.Fatalf("IncrBy: %v", err)
+					}
+				}
+				got, err := cms.Query(ctx, key, tt.query...)
+				if err != nil {
+					t.Fatalf("Query: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Query = %v, want %v", got, tt.want)
+				}
+			})
+		}
+		t.Run(backend+"/merge with weights", func(t *testing.T) {
+			cms, _ := build()
+			a, b, dest := testKey(t, "a"), testKey(t, "b"), testKey(t, "dest")
+			for _, key := range []string{a, b, dest} {
+				if err := cms.InitByDim(ctx, key, 2000, 5); err != nil {
+					t.Fatalf("InitByDim: %v", err)
+				}
+			}
+			cms.IncrBy(ctx, a, "x", 1)
+			cms.IncrBy(ctx, b, "x", 2)
+			cms.IncrBy(ctx, b, "y", 1)
+			if err := cms.Merge(ctx, dest, []string{a, b}, []int64{2, 3}); err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			got, _ := cms.Query(ctx, dest, "x", "y")
+			if want := []int64{8, 3}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Query = %v, want %v", got, want)
+			}
+		})
+		t.Run(backend+"/errors", func(t *testing.T) {
+			cms, _ := build()
+			key := testKey(t, "cms")
+			if _, err := cms.Query(ctx, key, "a"); err == nil {
+				t.Error("Query on missing key: want error")
+			}
+			cms.InitByDim(ctx, key, 2000, 5)
+			if err := cms.InitByDim(ctx, key, 2000, 5); err == nil {
+				t.Error("InitByDim twice: want error")
+			}
+		})
+	}
+}
+
+func TestTopK(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		adds  []string
+		list  []string
+		query map[string]bool
+		count map[string]int64
+	}{
+		{
+			name:  "keeps most frequent",
+			adds:  []string{"a", "a", "a", "b", "b", "c"},
+			list:  []string{"a", "b"},
+			query: map[string]bool{"a": true, "b": true, "c": false},
+			count: map[string]int64{"a": 3, "b": 2},
+		},
+		{
+			name:  "fewer than k",
+			adds:  []string{"a"},
+			list:  []string{"a"},
+			query: map[string]bool{"a": true, "z": false},
+			count: map[string]int64{"a": 1},
+		},
+	}
+	for backend, build := range counterBackends() {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				_, topk := build()
+				key := testKey(t, "topk")
+				if err := topk.Reserve(ctx, key, 2, 50, 5, 0.9); err != nil {
+					t.Fatalf("Reserve: %v", err)
+				}
+				if _, err := topk.Add(ctx, key, tt.adds...); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				list, err := topk.List(ctx, key)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				if !reflect.DeepEqual(list, tt.list) {
+					t.Errorf("List = %v, want %v", list, tt.list)
+				}
+				for item, want := range tt.query {
+					got, _ := topk.Query(ctx, key, item)
+					if len(got) != 1 || got[0] != want {
+						t.Errorf("Query(%s) = %v, want %v", item, got, want)
+					}
+				}
+				for item, want := range tt.count {
+					got, _ := topk.Count(ctx, key, item)
+					if len(got) != 1 || got[0] != want {
+						t.Errorf("Count(%s) = %v, want %v", item, got, want)
+					}
+				}
+			})
+		}
+		t.Run(backend+"/incrby expels", func(t *testing.T) {
+			_, topk := build()
+			key := testKey(t, "topk")
+			topk.Reserve(ctx, key, 2, 50, 5, 0.9)
+			topk.Add(ctx, key, "a", "a", "a", "b", "b")
+			expelled, err := topk.IncrBy(ctx, key, "d", 10)
+			if err != nil {
+				t.Fatalf("IncrBy: %v", err)
+			}
+			if expelled != "b" {
+				t.Errorf("expelled = %q, want b", expelled)
+			}
+			list, _ := topk.List(ctx, key)
+			if want := []string{"d", "a"}; !reflect.DeepEqual(list, want) {
+				t.Errorf("List = %v, want %v", list, want)
+			}
+		})
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	ctx := context.Background()
+	backends := map[string]func() HyperLogLog{
+		"memory": func() HyperLogLog { return NewMemoryHyperLogLog() },
+	}
+	if addr := os.Getenv("BOX_TEST_REDIS"); addr != "" {
+		backends["redis"] = func() HyperLogLog {
+			return &RedisHyperLogLog{Pool: newPool(Option{Host: addr})}
+		}
+	}
+	for backend, build := range backends {
+		t.Run(backend, func(t *testing.T) {
+			hll := build()
+			a, b, c, d := testKey(t, "a"), testKey(t, "b"), testKey(t, "c"), testKey(t, "d")
+			tests := []struct {
+				key     string
+				vals    []string
+				changed bool
+			}{
+				{key: a, vals: []string{"x", "y", "z"}, changed: true},
+				{key: a, vals: []string{"x", "y"}, changed: false},
+				{key: a, vals: []string{"w"}, changed: true},
+				{key: b, vals: []string{"w", "v"}, changed: true},
+			}
+			for _, tt := range tests {
+				changed, err := hll.Add(ctx, tt.key, tt.vals...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if changed != tt.changed {
+					t.Errorf("Add(%s, %v) = %v, want %v", tt.key, tt.vals, changed, tt.changed)
+				}
+			}
+			counts := []struct {
+				keys []string
+				want int64
+			}{
+				{keys: []string{a}, want: 4},
+				{keys: []string{b}, want: 2},
+				{keys: []string{a, b}, want: 5},
+				{keys: []string{testKey(t, "missing")}, want: 0},
+			}
+			for _, tt := range counts {
+				if got, err := hll.Count(ctx, tt.keys...); err != nil || got != tt.want {
+					t.Errorf("Count(%v) = %d %v, want %d", tt.keys, got, err, tt.want)
+				}
+			}
+			if err := hll.Merge(ctx, c, a, b); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := hll.Count(ctx, c); got != 5 {
+				t.Errorf("Count after Merge = %d, want 5", got)
+			}
+			// 合并至已有的键时保留原有的值
+			hll.Add(ctx, d, "u")
+			hll.Merge(ctx, d, b)
+			if got, _ := hll.Count(ctx, d); got != 3 {
+				t.Errorf("Count after Merge into existing = %d, want 3", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/aivencs/box/pkg/logger"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	// 快照文件格式
+	DUMP_MAGIC   = "BXBF"
+	DUMP_VERSION = 1
+	// 单个数据块的长度上限
+	DUMP_MAX_CHUNK = 512 << 20
+	// 键名的长度上限，与快照中记录长度的字段一致
+	DUMP_MAX_KEY = 1<<16 - 1
+)
+
+// 支持快照的过滤器
+type Dumper interface {
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader) error
+}
+
+// 快照中的数据块
+type chunk struct {
+	Iter int64
+	Data []byte
+}
+
+// 导出过滤器快照
+// 格式为: 标识 | 版本 | 键名长度 | 键名 | 数据块... | 结束块 | CRC32
+func (c *BloomFilter) Export(ctx context.Context, w io.Writer) error {
+	return c.ExportKey(ctx, c.Key, w)
+}
+
+// 导入过滤器快照至当前键
+func (c *BloomFilter) Import(ctx context.Context, r io.Reader) error {
+	_, err := c.ImportKey(ctx, c.Key, r)
+	return err
+}
+
+// 导出指定键的过滤器快照
+func (c *BloomFilter) ExportKey(ctx context.Context, key string, w io.Writer) error {
+	if len(key) > DUMP_MAX_KEY {
+		return logger.NewError(logger.LIMITERROR, "键名过长，无法写入快照", nil)
+	}
+	hash := crc32.NewIEEE()
+	bw := bufio.NewWriter(w)
+	mw := io.MultiWriter(bw, hash)
+	if err := writeHeader(mw, key); err != nil {
+		return logger.NewError(logger.EDERROR, "写入快照失败", err)
+	}
+	var iter int64
+	for {
+		next, data, err := c.Kernel.BfScanDump(key, iter)
+		if err != nil {
+			return logger.NewError(logger.CALLERROR, "读取过滤器失败", err)
+		}
+		if next == 0 {
+			break
+		}
+		if err := writeChunk(mw, chunk{Iter: next, Data: data}); err != nil {
+			return logger.NewError(logger.EDERROR, "写入快照失败", err)
+		}
+		iter = next
+	}
+	if iter == 0 {
+		return logger.NewError(logger.CHECK, "过滤器不存在", nil)
+	}
+	// 结束块
+	if err := writeChunk(mw, chunk{}); err != nil {
+		return logger.NewError(logger.EDERROR, "写入快照失败", err)
+	}
+	if err := binary.Write(bw, binary.BigEndian, hash.Sum32()); err != nil {
+		return logger.NewError(logger.EDERROR, "写入快照失败", err)
+	}
+	return bw.Flush()
+}
+
+// 导入快照至指定键，键名为空时使用快照中的键名
+// 校验通过后才会写入，目标键已存在时拒绝导入
+func (c *BloomFilter) ImportKey(ctx context.Context, key string, r io.Reader) (string, error) {
+	stored, chunks, err := readDump(r)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		key = stored
+	}
+	conn := c.Pool.Get()
+	exist, err := redigo.Bool(conn.Do("EXISTS", key))
+	conn.Close()
+	if err != nil {
+		return "", logger.NewError(logger.CALLERROR, "检查目标键失败", err)
+	}
+	if exist {
+		return "", logger.NewError(logger.RPERROR, "目标键已存在", nil)
+	}
+	for _, item := range chunks {
+		if _, err := c.Kernel.BfLoadChunk(key, item.Iter, item.Data); err != nil {
+			return "", logger.NewError(logger.CALLERROR, "写入过滤器失败", err)
+		}
+	}
+	return key, nil
+}
+
+// 读取并校验快照，返回键名与数据块
+func readDump(r io.Reader) (string, []chunk, error) {
+	hash := crc32.NewIEEE()
+	br := bufio.NewReader(r)
+	tr := io.TeeReader(br, hash)
+	key, err := readHeader(tr)
+	if err != nil {
+		return "", nil, err
+	}
+	chunks := []chunk{}
+	for {
+		item, err := readChunk(tr)
+		if err != nil {
+			return "", nil, logger.NewError(logger.EDERROR, "快照已损坏", err)
+		}
+		if item.Iter == 0 {
+			break
+		}
+		chunks = append(chunks, item)
+	}
+	var sum uint32
+	if err := binary.Read(br, binary.BigEndian, &sum); err != nil {
+		return "", nil, logger.NewError(logger.EDERROR, "快照已损坏", err)
+	}
+	if sum != hash.Sum32() {
+		return "", nil, logger.NewError(logger.DVERROR, "快照校验失败", nil)
+	}
+	return key, chunks, nil
+}
+
+func writeHeader(w io.Writer, key string) error {
+	if len(key) > DUMP_MAX_KEY {
+		return logger.NewError(logger.LIMITERROR, "键名过长，无法写入快照", nil)
+	}
+	if _, err := io.WriteString(w, DUMP_MAGIC); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(DUMP_VERSION)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(len(key))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, key)
+	return err
+}
+
+func readHeader(r io.Reader) (string, error) {
+	magic := make([]byte, len(DUMP_MAGIC))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return "", logger.NewError(logger.EDERROR, "快照已损坏", err)
+	}
+	if !bytes.Equal(magic, []byte(DUMP_MAGIC)) {
+		return "", logger.NewError(logger.EDERROR, "不是过滤器快照", nil)
+	}
+	var version, size uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return "", logger.NewError(logger.EDERROR, "快照已损坏", err)
+	}
+	if version != DUMP_VERSION {
+		return "", logger.NewError(logger.EDERROR, "不支持的快照版本", nil)
+	}
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return "", logger.NewError(logger.EDERROR, "快照已损坏", err)
+	}
+	key := make([]byte, size)
+	if _, err := io.ReadFull(r, key); err != nil {
+		return "", logger.NewError(logger.EDERROR, "快照已损坏", err)
+	}
+	return string(key), nil
+}
+
+func writeChunk(w io.Writer, item chunk) error {
+	if err := binary.Write(w, binary.BigEndian, item.Iter); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(item.Data))); err != nil {
+		return err
+	}
+	_, err := w.Write(item.Data)
+	return err
+}
+
+func readChunk(r io.Reader) (chunk, error) {
+	var item chunk
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &item.Iter); err != nil {
+		return item, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return item, err
+	}
+	if size > DUMP_MAX_CHUNK {
+		return item, logger.NewError(logger.LIMITERROR, "数据块过大", nil)
+	}
+	item.Data = make([]byte, size)
+	_, err := io.ReadFull(r, item.Data)
+	return item, err
+}
+
+// 导出InitFilter初始化的过滤器
+func Export(ctx context.Context, w io.Writer) error {
+	c, ok := filter.(Dumper)
+	if !ok {
+		return logger.NewError(logger.RPERROR, "过滤器不支持快照", nil)
+	}
+	return c.Export(ctx, w)
+}
+
+// 导入至InitFilter初始化的过滤器
+func Import(ctx context.Context, r io.Reader) error {
+	c, ok := filter.(Dumper)
+	if !ok {
+		return logger.NewError(logger.RPERROR, "过滤器不支持快照", nil)
+	}
+	return c.Import(ctx, r)
+}
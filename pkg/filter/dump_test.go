@@ -0,0 +1,92 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+// 按快照格式生成内容，与ExportKey写出的格式一致
+func buildDump(t *testing.T, key string, chunks []chunk) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	hash := crc32.NewIEEE()
+	w := io.MultiWriter(&buf, hash)
+	if err := writeHeader(w, key); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range append(chunks, chunk{}) {
+		if err := writeChunk(w, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binary.Write(&buf, binary.BigEndian, hash.Sum32())
+	return buf.Bytes()
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	chunks := []chunk{{Iter: 1, Data: []byte("header")}, {Iter: 9, Data: bytes.Repeat([]byte{0xff}, 1024)}, {Iter: 17}}
+	key, got, err := readDump(bytes.NewReader(buildDump(t, "seeds", chunks)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "seeds" || len(got) != len(chunks) {
+		t.Fatalf("readDump = %q %d chunks", key, len(got))
+	}
+	for i := range chunks {
+		if got[i].Iter != chunks[i].Iter || !bytes.Equal(got[i].Data, chunks[i].Data) {
+			t.Errorf("chunk %d = %+v, want %+v", i, got[i], chunks[i])
+		}
+	}
+}
+
+func TestDumpCorrupt(t *testing.T) {
+	valid := buildDump(t, "seeds", []chunk{{Iter: 1, Data: []byte("data")}})
+	corrupt := func(edit func(b []byte) []byte) []byte {
+		return edit(append([]byte(nil), valid...))
+	}
+	tests := []struct {
+		name string
+		dump []byte
+		code logger.Code
+	}{
+		{name: "bad crc", dump: corrupt(func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b }), code: logger.DVERROR},
+		{name: "flipped data", dump: corrupt(func(b []byte) []byte { b[len(b)-20] ^= 0x01; return b }), code: logger.DVERROR},
+		{name: "bad version", dump: corrupt(func(b []byte) []byte { b[5] = 2; return b }), code: logger.EDERROR},
+		{name: "bad magic", dump: corrupt(func(b []byte) []byte { b[0] = 'X'; return b }), code: logger.EDERROR},
+		{name: "truncated", dump: valid[:len(valid)-6], code: logger.EDERROR},
+		{name: "empty", dump: nil, code: logger.EDERROR},
+	}
+	for _, tt := range tests {
+		_, _, err := readDump(bytes.NewReader(tt.dump))
+		ers, ok := err.(*logger.BaseError)
+		if !ok || ers.Code() != tt.code {
+			t.Errorf("%s: err = %v, want code %d", tt.name, err, tt.code)
+		}
+	}
+}
+
+// 键名超出长度字段的范围时拒绝写入，避免生成损坏的快照
+func TestDumpLongKey(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, strings.Repeat("k", DUMP_MAX_KEY+1)); err == nil {
+		t.Fatal("writeHeader should reject long keys")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes", buf.Len())
+	}
+	bf := &BloomFilter{}
+	if err := bf.ExportKey(context.Background(), strings.Repeat("k", DUMP_MAX_KEY+1), &buf); err == nil {
+		t.Fatal("ExportKey should reject long keys")
+	}
+	key := strings.Repeat("k", DUMP_MAX_KEY)
+	if got, _, err := readDump(bytes.NewReader(buildDump(t, key, nil))); err != nil || got != key {
+		t.Errorf("max length key: %v", err)
+	}
+}
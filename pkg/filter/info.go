@@ -0,0 +1,201 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	// 定义默认值
+	DEFAULT_WATCH_THRESHOLD = 0.8
+	DEFAULT_WATCH_INTERVAL  = time.Minute
+)
+
+// 支持查询状态的过滤器
+type Inspector interface {
+	Info(ctx context.Context) (Information, error)
+}
+
+// 过滤器状态
+type Information struct {
+	Key           string `json:"key" label:"键名"`
+	Capacity      int64  `json:"capacity" label:"容量"`
+	Size          int64  `json:"size" label:"占用内存"`
+	Filters       int64  `json:"filters" label:"子过滤器数量"`
+	Inserted      int64  `json:"inserted" label:"已插入数量"`
+	ExpansionRate int64  `json:"expansion_rate" label:"扩容倍数" desc:"不扩容时为0"`
+}
+
+// 使用率
+func (c Information) Usage() float64 {
+	if c.Capacity == 0 {
+		return 0
+	}
+	return float64(c.Inserted) / float64(c.Capacity)
+}
+
+func (c *BloomFilter) Info(ctx context.Context) (Information, error) {
+	return infoKey(c.Pool, c.Key)
+}
+
+func (c *Manager) Info(ctx context.Context, name string) (Information, error) {
+	key, err := c.reserve(ctx, name)
+	if err != nil {
+		return Information{}, err
+	}
+	return infoKey(c.Pool, key)
+}
+
+func (c *namedFilter) Info(ctx context.Context) (Information, error) {
+	return c.manager.Info(ctx, c.name)
+}
+
+// 通过BF.INFO查询状态
+// 不扩容的过滤器其扩容倍数为空，故不直接使用redisbloom的Info
+func infoKey(pool *redigo.Pool, key string) (Information, error) {
+	r := pool.Get()
+	defer r.Close()
+	values, err := redigo.Values(r.Do("BF.INFO", key))
+	if err != nil {
+		return Information{}, logger.NewError(logger.CALLERROR, "查询过滤器状态失败", err)
+	}
+	return parseInfo(key, values)
+}
+
+// 解析BF.INFO的响应，响应为名称与值交替的列表
+func parseInfo(key string, values []interface{}) (Information, error) {
+	if len(values)%2 != 0 {
+		return Information{}, logger.NewError(logger.EDERROR, "过滤器状态格式有误", nil)
+	}
+	result := Information{Key: key}
+	for i := 0; i < len(values); i += 2 {
+		name, err := redigo.String(values[i], nil)
+		if err != nil {
+			return Information{}, logger.NewError(logger.EDERROR, "过滤器状态格式有误", err)
+		}
+		if values[i+1] == nil {
+			continue
+		}
+		val, err := redigo.Int64(values[i+1], nil)
+		if err != nil {
+			return Information{}, logger.NewError(logger.EDERROR, "过滤器状态格式有误", err)
+		}
+		switch name {
+		case "Capacity":
+			result.Capacity = val
+		case "Size":
+			result.Size = val
+		case "Number of filters":
+			result.Filters = val
+		case "Number of items inserted":
+			result.Inserted = val
+		case "Expansion rate":
+			result.ExpansionRate = val
+		}
+	}
+	return result, nil
+}
+
+// 查询InitFilter初始化的过滤器状态
+func Info(ctx context.Context) (Information, error) {
+	c, ok := filter.(Inspector)
+	if !ok {
+		return Information{}, logger.NewError(logger.RPERROR, "过滤器不支持查询状态", nil)
+	}
+	return c.Info(ctx)
+}
+
+// 容量监控所用参数
+type WatchOption struct {
+	Threshold float64       `json:"threshold" label:"告警阈值" desc:"已插入数量与容量之比，默认0.8" validate:"gte=0,lte=1"`
+	Interval  time.Duration `json:"interval" label:"检查间隔" desc:"默认一分钟"`
+}
+
+// 结构体
+// 定期检查过滤器使用率，超过阈值与恢复时各记录一次日志
+type Watchdog struct {
+	Kernel    Inspector
+	Threshold float64
+	Interval  time.Duration
+	mu        sync.Mutex
+	alerting  bool
+}
+
+// 创建容量监控对象
+func NewWatchdog(ctx context.Context, kernel Inspector, option WatchOption) *Watchdog {
+	if option.Threshold == 0 {
+		option.Threshold = DEFAULT_WATCH_THRESHOLD
+	}
+	if option.Interval == 0 {
+		option.Interval = DEFAULT_WATCH_INTERVAL
+	}
+	return &Watchdog{
+		Kernel:    kernel,
+		Threshold: option.Threshold,
+		Interval:  option.Interval,
+	}
+}
+
+// 检查一次，返回是否超过阈值
+// 仅在超过阈值与恢复时记录日志，日志未初始化时使用标准库输出
+func (c *Watchdog) Check(ctx context.Context) (bool, error) {
+	info, err := c.Kernel.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+	over := info.Usage() >= c.Threshold
+	c.mu.Lock()
+	changed := over != c.alerting
+	c.alerting = over
+	c.mu.Unlock()
+	if !changed {
+		return over, nil
+	}
+	attr := logger.Attr{
+		Oup: map[string]interface{}{
+			"info":      info,
+			"usage":     info.Usage(),
+			"threshold": c.Threshold,
+		},
+	}
+	remark := fmt.Sprintf("%s: %d/%d", info.Key, info.Inserted, info.Capacity)
+	if !over {
+		if !logger.Initialized() {
+			log.Printf("过滤器使用率已恢复 %s", remark)
+			return false, nil
+		}
+		attr.Monitor = logger.Monitor{Code: logger.SUCCESS, Level: logger.GetLevelBaseCode(logger.SUCCESS)}
+		logger.Info(ctx, logger.Message{Text: "过滤器使用率已恢复", Remark: remark, Attr: attr})
+		return false, nil
+	}
+	ers := logger.NewError(logger.LIMITERROR, "过滤器使用率超过阈值", nil).(*logger.BaseError)
+	if !logger.Initialized() {
+		log.Printf("%s %s", ers.Label(), remark)
+		return true, nil
+	}
+	attr.Monitor = logger.Monitor{Code: ers.Code(), Level: ers.Level()}
+	logger.Error(ctx, logger.Message{Text: ers.Label(), Remark: remark, Attr: attr})
+	return true, nil
+}
+
+// 定期检查直至ctx结束
+func (c *Watchdog) Work(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.Check(ctx); err != nil {
+			warn(ctx, "查询过滤器状态失败", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
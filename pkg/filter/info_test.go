@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   Information
+		code   logger.Code
+	}{
+		{
+			name: "scaling",
+			values: []interface{}{
+				[]byte("Capacity"), int64(1000), []byte("Size"), int64(2056), []byte("Number of filters"), int64(2),
+				[]byte("Number of items inserted"), int64(850), []byte("Expansion rate"), int64(2),
+			},
+			want: Information{Key: "seeds", Capacity: 1000, Size: 2056, Filters: 2, Inserted: 850, ExpansionRate: 2},
+		},
+		{
+			// 不扩容的过滤器扩容倍数为空
+			name: "nonscaling",
+			values: []interface{}{
+				[]byte("Capacity"), int64(100), []byte("Size"), int64(296), []byte("Number of filters"), int64(1),
+				[]byte("Number of items inserted"), int64(3), []byte("Expansion rate"), nil,
+			},
+			want: Information{Key: "seeds", Capacity: 100, Size: 296, Filters: 1, Inserted: 3},
+		},
+		{name: "unknown field", values: []interface{}{[]byte("Other"), int64(1), []byte("Capacity"), int64(5)}, want: Information{Key: "seeds", Capacity: 5}},
+		{name: "odd length", values: []interface{}{[]byte("Capacity")}, code: logger.EDERROR},
+		{name: "bad value", values: []interface{}{[]byte("Capacity"), []byte("many")}, code: logger.EDERROR},
+	}
+	for _, tt := range tests {
+		got, err := parseInfo("seeds", tt.values)
+		if tt.code != 0 {
+			if ers, ok := err.(*logger.BaseError); !ok || ers.Code() != tt.code {
+				t.Errorf("%s: err = %v, want code %d", tt.name, err, tt.code)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: parseInfo = %+v %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+	if usage := (Information{Capacity: 1000, Inserted: 850}).Usage(); usage != 0.85 {
+		t.Errorf("Usage = %v", usage)
+	}
+}
+
+// 按顺序返回预设的状态
+type fakeInspector struct {
+	usages []int64
+}
+
+func (c *fakeInspector) Info(ctx context.Context) (Information, error) {
+	inserted := c.usages[0]
+	c.usages = c.usages[1:]
+	return Information{Key: "seeds", Capacity: 100, Inserted: inserted}, nil
+}
+
+// 仅在超过阈值与恢复时各记录一次
+func TestWatchdogTransitions(t *testing.T) {
+	if logger.Initialized() {
+		t.Skip("logger already initialized")
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	usages := []int64{50, 90, 95, 70, 60, 85}
+	wants := []bool{false, true, true, false, false, true}
+	c := NewWatchdog(context.Background(), &fakeInspector{usages: usages}, WatchOption{})
+	for i, want := range wants {
+		if over, err := c.Check(context.Background()); err != nil || over != want {
+			t.Errorf("check %d: over = %v %v, want %v", i, over, err, want)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	for i, text := range []string{"超过阈值", "已恢复", "超过阈值"} {
+		if !strings.Contains(lines[i], text) {
+			t.Errorf("line %d = %q, want %q", i, lines[i], text)
+		}
+	}
+}
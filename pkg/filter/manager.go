@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"context"
+	"sync"
+
+	redisbloom "github.com/RedisBloom/redisbloom-go"
+	"github.com/aivencs/box/pkg/kit"
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/aivencs/box/pkg/validate"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	// 定义默认值
+	DEFAULT_CAPACITY   = 100000
+	DEFAULT_ERROR_RATE = 0.001
+	// RedisBloom在键已存在时返回的错误
+	ERR_ITEM_EXISTS = "ERR item exists"
+)
+
+// 受管理的单个过滤器所用参数
+type Item struct {
+	Name      string  `json:"name" label:"名称" validate:"required"`
+	Key       string  `json:"key" label:"键名" desc:"默认为Option.Key与名称拼接"`
+	Capacity  uint64  `json:"capacity" label:"容量" desc:"默认为十万"`
+	ErrorRate float64 `json:"error_rate" label:"误判率" desc:"默认为千分之一" validate:"gte=0,lt=1"`
+}
+
+// 结构体
+// 多个具名过滤器共用同一个连接池
+type Manager struct {
+	Kernel *redisbloom.Client
+	Pool   *redigo.Pool
+	Prefix string
+	mu     sync.Mutex
+	items  map[string]*managed
+}
+
+// 受管理的过滤器
+type managed struct {
+	item     Item
+	reserved bool
+}
+
+// 创建过滤器管理对象
+func NewManager(ctx context.Context, option Option, items ...Item) (*Manager, error) {
+	message, err := validate.Work(ctx, option)
+	if err != nil {
+		return nil, logger.NewError(logger.PVERROR, message, err)
+	}
+	applyOption(&option)
+	pool := newPool(option)
+	c := &Manager{
+		Kernel: redisbloom.NewClientFromPool(pool, option.Key),
+		Pool:   pool,
+		Prefix: option.Key,
+		items:  map[string]*managed{},
+	}
+	for _, item := range items {
+		if err := c.Register(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// 注册过滤器
+// 注册时不会访问服务端，首次使用时才会创建
+func (c *Manager) Register(ctx context.Context, item Item) error {
+	message, err := validate.Work(ctx, item)
+	if err != nil {
+		return logger.NewError(logger.PVERROR, message, err)
+	}
+	if item.Key == "" {
+		item.Key = item.Name
+		if c.Prefix != "" {
+			item.Key = kit.JoinString(c.Prefix, ":", item.Name)
+		}
+	}
+	if item.Capacity == 0 {
+		item.Capacity = DEFAULT_CAPACITY
+	}
+	if item.ErrorRate == 0 {
+		item.ErrorRate = DEFAULT_ERROR_RATE
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[item.Name]; ok {
+		return logger.NewError(logger.RPERROR, "过滤器已存在", nil)
+	}
+	c.items[item.Name] = &managed{item: item}
+	return nil
+}
+
+// 获取具名过滤器
+func (c *Manager) Get(ctx context.Context, name string) (Filter, error) {
+	if _, err := c.reserve(ctx, name); err != nil {
+		return nil, err
+	}
+	return &namedFilter{manager: c, name: name}, nil
+}
+
+// 已注册的过滤器
+func (c *Manager) Items() []Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := []Item{}
+	for _, m := range c.items {
+		result = append(result, m.item)
+	}
+	return result
+}
+
+func (c *Manager) Add(ctx context.Context, name string, val string) (bool, error) {
+	key, err := c.reserve(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return c.Kernel.Add(key, val)
+}
+
+func (c *Manager) Exist(ctx context.Context, name string, val string) (bool, error) {
+	key, err := c.reserve(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return c.Kernel.Exists(key, val)
+}
+
+// 按需创建过滤器并返回其键名
+func (c *Manager) reserve(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.items[name]
+	if !ok {
+		return "", logger.NewError(logger.RPERROR, "过滤器未注册", nil)
+	}
+	if m.reserved {
+		return m.item.Key, nil
+	}
+	err := c.Kernel.Reserve(m.item.Key, m.item.ErrorRate, m.item.Capacity)
+	// 键已存在时沿用已有的过滤器
+	if e, ok := err.(redigo.Error); err != nil && !(ok && string(e) == ERR_ITEM_EXISTS) {
+		return "", logger.NewError(logger.CALLERROR, "创建过滤器失败", err)
+	}
+	m.reserved = true
+	return m.item.Key, nil
+}
+
+// 绑定名称的过滤器
+type namedFilter struct {
+	manager *Manager
+	name    string
+}
+
+func (c *namedFilter) Add(ctx context.Context, val string) (bool, error) {
+	return c.manager.Add(ctx, c.name, val)
+}
+
+func (c *namedFilter) Exist(ctx context.Context, val string) (bool, error) {
+	return c.manager.Exist(ctx, c.name, val)
+}
@@ -0,0 +1,17 @@
+package filter
+
+import (
+	"context"
+	"testing"
+)
+
+// 未指定时使用默认的连接池参数
+func TestNewManagerPool(t *testing.T) {
+	c, err := NewManager(context.Background(), Option{Host: "127.0.0.1:6379", MaxIdle: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Pool.MaxIdle != 5 || c.Pool.MaxActive != DEFAULT_MAXACTIVE || c.Pool.IdleTimeout != DEFAULT_IDLE_TIMEOUT {
+		t.Errorf("pool = %d %d %s", c.Pool.MaxIdle, c.Pool.MaxActive, c.Pool.IdleTimeout)
+	}
+}
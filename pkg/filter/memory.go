@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 结构体
+// 基于内存的布隆过滤器
+type MemoryBloomFilter struct {
+	mu     sync.RWMutex
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// 创建基于内存的布隆过滤器
+func NewMemoryBloomFilter(capacity uint64, errorRate float64) *MemoryBloomFilter {
+	if capacity == 0 {
+		capacity = DEFAULT_CAPACITY
+	}
+	if errorRate <= 0 || errorRate >= 1 {
+		errorRate = DEFAULT_ERROR_RATE
+	}
+	// m = -n*ln(p)/(ln2)^2, k = m/n*ln2
+	size := uint64(math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Ceil(float64(size) / float64(capacity) * math.Ln2))
+	return &MemoryBloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+func (c *MemoryBloomFilter) Add(ctx context.Context, val string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exist := true
+	for _, i := range c.locate(val) {
+		if c.bits[i/64]&(1<<(i%64)) == 0 {
+			exist = false
+			c.bits[i/64] |= 1 << (i % 64)
+		}
+	}
+	// 与BF.ADD一致，新增时返回true
+	return !exist, nil
+}
+
+func (c *MemoryBloomFilter) Exist(ctx context.Context, val string) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, i := range c.locate(val) {
+		if c.bits[i/64]&(1<<(i%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 清空
+func (c *MemoryBloomFilter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bits = make([]uint64, len(c.bits))
+}
+
+// 双重哈希计算位置
+func (c *MemoryBloomFilter) locate(val string) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(val))
+	sum := h.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32
+	result := make([]uint64, c.hashes)
+	for i := uint64(0); i < c.hashes; i++ {
+		result[i] = (h1 + i*h2) % c.size
+	}
+	return result
+}
@@ -17,6 +17,7 @@ type TypeSupport string
 
 const (
 	BLOOM TypeSupport = "bloom"
+	SET   TypeSupport = "set"
 	// 定义默认值
 	DEFAULT_MAXIDLE      = 20
 	DEFAULT_IDLE_TIMEOUT = 120 * time.Second
@@ -51,6 +52,7 @@ type Option struct {
 	IdleTimeout time.Duration `json:"idle_timeout" label:"空闲超时时间"`
 	MaxActive   int           `json:"max_active" label:"最大链接数"`
 	Key         string        `json:"key" label:"键名"`
+	Buckets     int           `json:"buckets" label:"分桶数量" desc:"仅用于set，默认不分桶"`
 }
 
 // 初始化对象
@@ -79,6 +81,8 @@ func FilterFactory(ctx context.Context, support TypeSupport, option Option) (Fil
 	switch support {
 	case BLOOM:
 		return NewBloomFilter(ctx, option)
+	case SET:
+		return NewSetFilter(ctx, option)
 	default:
 		return NewBloomFilter(ctx, option)
 	}
@@ -94,9 +98,20 @@ type BloomFilter struct {
 
 // 创建基于的对象
 func NewBloomFilter(ctx context.Context, option Option) (Filter, error) {
-	applyOption(option)
+	applyOption(&option)
 	var err error
-	pool := &redigo.Pool{
+	pool := newPool(option)
+	rbc := redisbloom.NewClientFromPool(pool, option.Key)
+	return &BloomFilter{
+		Kernel: rbc,
+		Pool:   pool,
+		Key:    option.Key,
+	}, err
+}
+
+// 创建连接池
+func newPool(option Option) *redigo.Pool {
+	return &redigo.Pool{
 		MaxIdle:     option.MaxIdle,
 		IdleTimeout: option.IdleTimeout,
 		MaxActive:   option.MaxActive,
@@ -111,6 +126,9 @@ func NewBloomFilter(ctx context.Context, option Option) (Filter, error) {
 					c.Close()
 					return nil, err
 				}
+			}
+			// 未鉴权时同样需要切换数据库
+			if option.DB != 0 {
 				if _, err := c.Do("SELECT", option.DB); err != nil {
 					c.Close()
 					return nil, err
@@ -123,15 +141,9 @@ func NewBloomFilter(ctx context.Context, option Option) (Filter, error) {
 			return err
 		},
 	}
-	rbc := redisbloom.NewClientFromPool(pool, option.Key)
-	return &BloomFilter{
-		Kernel: rbc,
-		Pool:   pool,
-		Key:    option.Key,
-	}, err
 }
 
-func applyOption(option Option) {
+func applyOption(option *Option) {
 	if option.MaxIdle == 0 {
 		option.MaxIdle = DEFAULT_MAXIDLE
 	}
@@ -0,0 +1,46 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// 进程内的Redis，支持集合等基础命令，不支持RedisBloom命令
+func newTestRedis(t *testing.T, option Option) (*miniredis.Miniredis, *redigo.Pool) {
+	t.Helper()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	if option.Auth {
+		server.RequireAuth(option.Password)
+	}
+	option.Host = server.Addr()
+	applyOption(&option)
+	pool := newPool(option)
+	t.Cleanup(func() { pool.Close() })
+	return server, pool
+}
+
+// 无论是否鉴权都切换至指定的数据库
+func TestPoolSelectsDB(t *testing.T) {
+	tests := []Option{
+		{DB: 2},
+		{DB: 3, Auth: true, Password: "secret"},
+		{Auth: true, Password: "secret"},
+	}
+	for _, option := range tests {
+		server, pool := newTestRedis(t, option)
+		conn := pool.Get()
+		if _, err := conn.Do("SET", "k", "v"); err != nil {
+			t.Fatalf("%+v: %v", option, err)
+		}
+		conn.Close()
+		if got, err := server.DB(option.DB).Get("k"); err != nil || got != "v" {
+			t.Errorf("%+v: db %d has %q %v", option, option.DB, got, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package filter
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+const (
+	// 定义默认值
+	DEFAULT_PROBE_INTERVAL = 5 * time.Second
+	DEFAULT_JOURNAL_LIMIT  = 100000
+)
+
+// 降级包装所用参数
+type ResilientOption struct {
+	Capacity      uint64        `json:"capacity" label:"本地过滤器容量" desc:"默认为十万"`
+	ErrorRate     float64       `json:"error_rate" label:"本地过滤器误判率" desc:"默认为千分之一"`
+	ProbeInterval time.Duration `json:"probe_interval" label:"恢复检查间隔" desc:"默认五秒"`
+	JournalLimit  int           `json:"journal_limit" label:"待回放记录上限" desc:"默认为十万"`
+}
+
+// 结构体
+// 服务端不可用时降级至本地布隆过滤器，恢复后回放期间的写入
+// 日志未初始化时使用标准库输出
+type ResilientFilter struct {
+	Kernel        Filter
+	Local         *MemoryBloomFilter
+	ProbeInterval time.Duration
+	JournalLimit  int
+	mu            sync.Mutex
+	degraded      bool
+	probing       bool
+	lastProbe     time.Time
+	journal       []string
+	overflow      bool
+}
+
+// 创建可降级的过滤器
+func NewResilientFilter(ctx context.Context, kernel Filter, option ResilientOption) *ResilientFilter {
+	if option.ProbeInterval == 0 {
+		option.ProbeInterval = DEFAULT_PROBE_INTERVAL
+	}
+	if option.JournalLimit == 0 {
+		option.JournalLimit = DEFAULT_JOURNAL_LIMIT
+	}
+	return &ResilientFilter{
+		Kernel:        kernel,
+		Local:         NewMemoryBloomFilter(option.Capacity, option.ErrorRate),
+		ProbeInterval: option.ProbeInterval,
+		JournalLimit:  option.JournalLimit,
+	}
+}
+
+// 是否处于降级状态
+func (c *ResilientFilter) Degraded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.degraded
+}
+
+func (c *ResilientFilter) Add(ctx context.Context, val string) (bool, error) {
+	if c.available(ctx) {
+		res, err := c.Kernel.Add(ctx, val)
+		if err == nil {
+			return res, nil
+		}
+		c.degrade(ctx, err)
+	}
+	c.mu.Lock()
+	if len(c.journal) < c.JournalLimit {
+		c.journal = append(c.journal, val)
+	} else if !c.overflow {
+		c.overflow = true
+		warn(ctx, "待回放记录已达上限，后续写入将不会回放", nil)
+	}
+	c.mu.Unlock()
+	return c.Local.Add(ctx, val)
+}
+
+func (c *ResilientFilter) Exist(ctx context.Context, val string) (bool, error) {
+	if c.available(ctx) {
+		res, err := c.Kernel.Exist(ctx, val)
+		if err == nil {
+			return res, nil
+		}
+		c.degrade(ctx, err)
+	}
+	return c.Local.Exist(ctx, val)
+}
+
+// 判断服务端是否可用，降级期间按间隔尝试恢复
+// 回放在锁外进行，服务端较慢时不阻塞其他调用
+func (c *ResilientFilter) available(ctx context.Context) bool {
+	c.mu.Lock()
+	if !c.degraded {
+		c.mu.Unlock()
+		return true
+	}
+	if c.probing || time.Since(c.lastProbe) < c.ProbeInterval {
+		c.mu.Unlock()
+		return false
+	}
+	c.lastProbe = time.Now()
+	c.probing = true
+	c.mu.Unlock()
+	for {
+		// 取出待回放的写入，回放期间新增的写入在下一轮处理
+		c.mu.Lock()
+		batch := c.journal
+		c.journal = nil
+		if len(batch) == 0 {
+			c.overflow = false
+			c.degraded = false
+			c.probing = false
+			c.Local.Reset()
+			c.mu.Unlock()
+			break
+		}
+		c.mu.Unlock()
+		for i, val := range batch {
+			if _, err := c.Kernel.Add(ctx, val); err != nil {
+				c.mu.Lock()
+				c.journal = append(batch[i:], c.journal...)
+				c.probing = false
+				c.mu.Unlock()
+				return false
+			}
+		}
+	}
+	notice(ctx, "过滤器服务已恢复")
+	return true
+}
+
+func (c *ResilientFilter) degrade(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.degraded {
+		return
+	}
+	c.degraded = true
+	c.lastProbe = time.Now()
+	warn(ctx, "过滤器服务不可用，已降级至本地过滤器", err)
+}
+
+func warn(ctx context.Context, text string, err error) {
+	ers := logger.NewError(logger.RWARN, text, err).(*logger.BaseError)
+	if !logger.Initialized() {
+		log.Printf("%s %s", ers.Label(), ers.UnWrapError())
+		return
+	}
+	logger.Warn(ctx, logger.Message{
+		Text:      ers.Label(),
+		Traceback: ers.UnWrapError(),
+		Attr: logger.Attr{
+			Monitor: logger.Monitor{
+				Code:  ers.Code(),
+				Level: ers.Level(),
+			},
+		},
+	})
+}
+
+func notice(ctx context.Context, text string) {
+	if !logger.Initialized() {
+		log.Print(text)
+		return
+	}
+	logger.Info(ctx, logger.Message{
+		Text: text,
+		Attr: logger.Attr{
+			Monitor: logger.Monitor{
+				Code:  logger.SUCCESS,
+				Level: logger.GetLevelBaseCode(logger.SUCCESS),
+			},
+		},
+	})
+}
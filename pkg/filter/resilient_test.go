@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 服务端中断期间写入本地过滤器，恢复后回放至服务端
+func TestResilientReplay(t *testing.T) {
+	ctx := context.Background()
+	server, pool := newTestRedis(t, Option{})
+	kernel := &SetFilter{Pool: pool, Key: "seeds"}
+	c := NewResilientFilter(ctx, kernel, ResilientOption{Capacity: 1000, ProbeInterval: 20 * time.Millisecond})
+	if ok, err := c.Add(ctx, "a"); err != nil || !ok {
+		t.Fatalf("Add(a) = %v %v", ok, err)
+	}
+	server.Close()
+	for _, val := range []string{"b", "c"} {
+		if ok, err := c.Add(ctx, val); err != nil || !ok {
+			t.Fatalf("Add(%s) while down = %v %v", val, ok, err)
+		}
+	}
+	if !c.Degraded() {
+		t.Fatal("should degrade when Redis is down")
+	}
+	// 降级期间以本地结果作答，且不会频繁探测服务端
+	if ok, err := c.Exist(ctx, "b"); err != nil || !ok {
+		t.Errorf("Exist(b) while down = %v %v", ok, err)
+	}
+	if ok, _ := c.Exist(ctx, "z"); ok {
+		t.Error("Exist(z) while down should be false")
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	// 探测间隔内仍使用本地过滤器
+	if !c.Degraded() {
+		t.Fatal("recovered before the probe interval")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, err := c.Exist(ctx, "c"); err != nil || !ok {
+		t.Fatalf("Exist(c) after restore = %v %v", ok, err)
+	}
+	if c.Degraded() {
+		t.Fatal("should recover after Redis is restored")
+	}
+	members, err := server.Members("seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 3 {
+		t.Errorf("members = %v, want a b c", members)
+	}
+	if ok, _ := c.Local.Exist(ctx, "b"); ok {
+		t.Error("local filter should be reset after replay")
+	}
+}
+
+// 回放失败时保留未回放的记录，下次探测继续
+func TestResilientReplayFails(t *testing.T) {
+	ctx := context.Background()
+	server, pool := newTestRedis(t, Option{})
+	c := NewResilientFilter(ctx, &SetFilter{Pool: pool, Key: "seeds"}, ResilientOption{ProbeInterval: 10 * time.Millisecond})
+	server.Close()
+	c.Add(ctx, "a")
+	c.Add(ctx, "b")
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.Exist(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Degraded() || len(c.journal) != 2 {
+		t.Fatalf("degraded = %v journal = %v", c.Degraded(), c.journal)
+	}
+	server.Restart()
+	time.Sleep(20 * time.Millisecond)
+	c.Exist(ctx, "a")
+	if members, _ := server.Members("seeds"); c.Degraded() || len(members) != 2 {
+		t.Errorf("degraded = %v members = %v", c.Degraded(), members)
+	}
+}
@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"context"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/aivencs/box/pkg/kit"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// 结构体
+// 基于Redis集合，无误判
+type SetFilter struct {
+	Pool    *redigo.Pool
+	Key     string
+	Buckets int
+}
+
+// 创建基于Redis集合的对象
+// 分桶时按值的哈希写入多个小集合，以便Redis使用紧凑编码
+func NewSetFilter(ctx context.Context, option Option) (Filter, error) {
+	applyOption(&option)
+	return &SetFilter{
+		Pool:    newPool(option),
+		Key:     option.Key,
+		Buckets: option.Buckets,
+	}, nil
+}
+
+func (c *SetFilter) Add(ctx context.Context, val string) (bool, error) {
+	r := c.Pool.Get()
+	defer r.Close()
+	return redigo.Bool(r.Do("SADD", c.bucket(val), val))
+}
+
+func (c *SetFilter) Exist(ctx context.Context, val string) (bool, error) {
+	r := c.Pool.Get()
+	defer r.Close()
+	return redigo.Bool(r.Do("SISMEMBER", c.bucket(val), val))
+}
+
+// 计算值所在的键
+func (c *SetFilter) bucket(val string) string {
+	if c.Buckets <= 1 {
+		return c.Key
+	}
+	index := crc32.ChecksumIEEE([]byte(val)) % uint32(c.Buckets)
+	return kit.JoinString(c.Key, ":", strconv.FormatUint(uint64(index), 10))
+}
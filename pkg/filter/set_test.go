@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSetFilter(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newTestRedis(t, Option{})
+	for _, buckets := range []int{0, 4} {
+		key := fmt.Sprintf("seeds-%d", buckets)
+		f, err := NewSetFilter(ctx, Option{Host: server.Addr(), Key: key, Buckets: buckets})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := f.(*SetFilter)
+		if c.Pool.MaxActive != DEFAULT_MAXACTIVE || c.Pool.MaxIdle != DEFAULT_MAXIDLE {
+			t.Errorf("pool = %d %d, want defaults", c.Pool.MaxActive, c.Pool.MaxIdle)
+		}
+		vals := []string{}
+		for i := 0; i < 50; i++ {
+			vals = append(vals, fmt.Sprintf("item-%d", i))
+		}
+		for _, val := range vals {
+			if ok, err := c.Add(ctx, val); err != nil || !ok {
+				t.Fatalf("Add(%s) = %v %v", val, ok, err)
+			}
+		}
+		// 重复写入返回false
+		if ok, _ := c.Add(ctx, vals[0]); ok {
+			t.Error("duplicate Add should return false")
+		}
+		for _, val := range vals {
+			if ok, err := c.Exist(ctx, val); err != nil || !ok {
+				t.Errorf("Exist(%s) = %v %v", val, ok, err)
+			}
+		}
+		// 精确判断，不存在的值均返回false
+		for i := 0; i < 1000; i++ {
+			if ok, _ := c.Exist(ctx, fmt.Sprintf("other-%d", i)); ok {
+				t.Fatalf("other-%d reported as existing", i)
+			}
+		}
+		keys := 0
+		total := 0
+		for _, name := range server.Keys() {
+			if members, err := server.Members(name); err == nil && len(name) >= len(key) && name[:len(key)] == key {
+				keys++
+				total += len(members)
+			}
+		}
+		want := buckets
+		if want == 0 {
+			want = 1
+		}
+		if keys != want || total != len(vals) {
+			t.Errorf("buckets %d: %d keys with %d members, want %d keys", buckets, keys, total, want)
+		}
+	}
+}
+
+// 固定返回存在的过滤器，模拟布隆过滤器的误判
+type alwaysFilter struct{}
+
+func (alwaysFilter) Add(ctx context.Context, val string) (bool, error)   { return false, nil }
+func (alwaysFilter) Exist(ctx context.Context, val string) (bool, error) { return true, nil }
+
+func TestShadowFilter(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newTestRedis(t, Option{})
+	exact, _ := NewSetFilter(ctx, Option{Host: server.Addr(), Key: "seeds"})
+	tests := []struct {
+		preferExact bool
+		want        bool
+	}{
+		{preferExact: false, want: true},
+		{preferExact: true, want: false},
+	}
+	for _, tt := range tests {
+		c := NewShadowFilter(ctx, alwaysFilter{}, exact, tt.preferExact)
+		if _, err := c.Add(ctx, "a"); err != nil {
+			t.Fatal(err)
+		}
+		for _, val := range []string{"a", "b", "c", "d"} {
+			got, err := c.Exist(ctx, val)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if val != "a" && got != tt.want {
+				t.Errorf("preferExact %v: Exist(%s) = %v, want %v", tt.preferExact, val, got, tt.want)
+			}
+		}
+		report := c.Report()
+		want := ShadowReport{Checks: 4, Negatives: 3, FalsePositives: 3, Rate: 1}
+		if report != want {
+			t.Errorf("report = %+v, want %+v", report, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aivencs/box/pkg/logger"
+)
+
+// 对照统计结果
+type ShadowReport struct {
+	Checks         int64   `json:"checks" label:"查询次数"`
+	Negatives      int64   `json:"negatives" label:"精确结果为不存在的次数"`
+	FalsePositives int64   `json:"false_positives" label:"误判次数"`
+	Rate           float64 `json:"rate" label:"误判率"`
+}
+
+// 结构体
+// 布隆过滤器与精确过滤器并行运行，统计实际误判率
+type ShadowFilter struct {
+	Bloom       Filter
+	Exact       Filter
+	PreferExact bool
+	mu          sync.Mutex
+	report      ShadowReport
+}
+
+// 创建对照过滤器
+// preferExact为真时以精确过滤器的结果作为返回值
+func NewShadowFilter(ctx context.Context, bloom Filter, exact Filter, preferExact bool) *ShadowFilter {
+	return &ShadowFilter{
+		Bloom:       bloom,
+		Exact:       exact,
+		PreferExact: preferExact,
+	}
+}
+
+func (c *ShadowFilter) Add(ctx context.Context, val string) (bool, error) {
+	bloom, err := c.Bloom.Add(ctx, val)
+	if err != nil {
+		return false, err
+	}
+	exact, err := c.Exact.Add(ctx, val)
+	if err != nil {
+		return false, err
+	}
+	if c.PreferExact {
+		return exact, nil
+	}
+	return bloom, nil
+}
+
+func (c *ShadowFilter) Exist(ctx context.Context, val string) (bool, error) {
+	bloom, err := c.Bloom.Exist(ctx, val)
+	if err != nil {
+		return false, err
+	}
+	exact, err := c.Exact.Exist(ctx, val)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	c.report.Checks++
+	if !exact {
+		c.report.Negatives++
+		if bloom {
+			c.report.FalsePositives++
+		}
+	}
+	c.mu.Unlock()
+	if c.PreferExact {
+		return exact, nil
+	}
+	return bloom, nil
+}
+
+// 当前统计结果
+func (c *ShadowFilter) Report() ShadowReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := c.report
+	if result.Negatives > 0 {
+		result.Rate = float64(result.FalsePositives) / float64(result.Negatives)
+	}
+	return result
+}
+
+// 定期输出统计结果直至ctx结束
+func (c *ShadowFilter) Work(ctx context.Context, interval time.Duration) {
+	if interval == 0 {
+		interval = DEFAULT_WATCH_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logger.Info(ctx, logger.Message{
+				Text: "过滤器误判统计",
+				Attr: logger.Attr{
+					Monitor: logger.Monitor{
+						Code:  logger.SUCCESS,
+						Level: logger.GetLevelBaseCode(logger.SUCCESS),
+					},
+					Oup: map[string]interface{}{
+						"report": c.Report(),
+					},
+				},
+			})
+		}
+	}
+}
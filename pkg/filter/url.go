@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/aivencs/box/pkg/kit"
+	"github.com/aivencs/box/pkg/logger"
+)
+
+// 默认剔除的追踪参数，以*结尾表示前缀匹配
+var DEFAULT_TRACKING_PARAMS = []string{"utm_*", "spm", "fbclid", "gclid", "yclid", "msclkid"}
+
+// 默认端口
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// 网址过滤所用参数
+type URLOption struct {
+	Base           string   `json:"base" label:"基础网址" desc:"用于解析相对链接"`
+	TrackingParams []string `json:"tracking_params" label:"追踪参数" desc:"规范化时剔除，默认为DEFAULT_TRACKING_PARAMS"`
+}
+
+// 结构体
+// 网址规范化后再以摘要去重
+type URLFilter struct {
+	Kernel         Filter
+	Base           string
+	TrackingParams []string
+}
+
+// 创建网址过滤对象
+// kernel为空时使用InitFilter初始化的过滤器
+func NewURLFilter(ctx context.Context, kernel Filter, option URLOption) *URLFilter {
+	if option.TrackingParams == nil {
+		option.TrackingParams = DEFAULT_TRACKING_PARAMS
+	}
+	return &URLFilter{
+		Kernel:         kernel,
+		Base:           option.Base,
+		TrackingParams: option.TrackingParams,
+	}
+}
+
+// 规范化网址
+// 协议与主机转小写，去除默认端口、锚点与追踪参数，参数按名称排序
+func (c *URLFilter) Canonicalize(base string, link string) (string, error) {
+	var err error
+	if base != "" {
+		link, err = kit.JoinLink(base, link)
+		if err != nil {
+			return "", logger.NewError(logger.RPERROR, "网址解析失败", err)
+		}
+	}
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", logger.NewError(logger.RPERROR, "网址解析失败", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", logger.NewError(logger.RPERROR, "网址不完整", nil)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := u.Hostname(), u.Port()
+	host = strings.ToLower(host)
+	if port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6地址需保留方括号
+		host = kit.JoinString("[", host, "]")
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	query := u.Query()
+	for name := range query {
+		if c.isTracking(name) {
+			query.Del(name)
+		}
+	}
+	// Encode会按参数名排序
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// 生成网址指纹
+func (c *URLFilter) Fingerprint(base string, link string) (string, error) {
+	canonical, err := c.Canonicalize(base, link)
+	if err != nil {
+		return "", err
+	}
+	return kit.CreateDigest(canonical), nil
+}
+
+func (c *URLFilter) kernel() Filter {
+	if c.Kernel == nil {
+		return filter
+	}
+	return c.Kernel
+}
+
+func (c *URLFilter) isTracking(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range c.TrackingParams {
+		param = strings.ToLower(param)
+		if strings.HasSuffix(param, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(param, "*")) {
+				return true
+			}
+			continue
+		}
+		if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *URLFilter) Add(ctx context.Context, link string) (bool, error) {
+	return c.AddFrom(ctx, c.Base, link)
+}
+
+func (c *URLFilter) Exist(ctx context.Context, link string) (bool, error) {
+	return c.ExistFrom(ctx, c.Base, link)
+}
+
+// 以所在页面为基础添加链接
+func (c *URLFilter) AddFrom(ctx context.Context, page string, link string) (bool, error) {
+	fingerprint, err := c.Fingerprint(page, link)
+	if err != nil {
+		return false, err
+	}
+	return c.kernel().Add(ctx, fingerprint)
+}
+
+// 以所在页面为基础判断链接是否存在
+func (c *URLFilter) ExistFrom(ctx context.Context, page string, link string) (bool, error) {
+	fingerprint, err := c.Fingerprint(page, link)
+	if err != nil {
+		return false, err
+	}
+	return c.kernel().Exist(ctx, fingerprint)
+}
@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := NewURLFilter(context.Background(), nil, URLOption{})
+	tests := []struct {
+		name string
+		base string
+		link string
+		want string
+	}{
+		{name: "lower scheme and host", link: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "strip default http port", link: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "strip default https port", link: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "keep other port", link: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{name: "ipv6", link: "http://[2001:DB8::1]/a", want: "http://[2001:db8::1]/a"},
+		{name: "ipv6 default port", link: "https://[2001:db8::1]:443/a", want: "https://[2001:db8::1]/a"},
+		{name: "ipv6 other port", link: "http://[2001:db8::1]:8080/a", want: "http://[2001:db8::1]:8080/a"},
+		{name: "empty path", link: "https://example.com", want: "https://example.com/"},
+		{name: "drop fragment", link: "https://example.com/a#top", want: "https://example.com/a"},
+		{name: "sort query", link: "https://example.com/a?b=2&a=1&c=3", want: "https://example.com/a?a=1&b=2&c=3"},
+		{name: "drop tracking params", link: "https://example.com/a?utm_source=x&UTM_Medium=y&id=1&fbclid=z&spm=w", want: "https://example.com/a?id=1"},
+		{name: "drop empty query", link: "https://example.com/a?", want: "https://example.com/a"},
+		{name: "join relative", base: "https://example.com/list/page.html", link: "../item?id=2", want: "https://example.com/item?id=2"},
+		{name: "join absolute path", base: "https://example.com/list/", link: "/item", want: "https://example.com/item"},
+		{name: "join absolute link", base: "https://example.com/list/", link: "http://other.com/x", want: "http://other.com/x"},
+	}
+	for _, tt := range tests {
+		got, err := c.Canonicalize(tt.base, tt.link)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Canonicalize(%q, %q) = %q, want %q", tt.name, tt.base, tt.link, got, tt.want)
+		}
+	}
+	for _, link := range []string{"/relative", "example.com/a", "http://%zz"} {
+		if _, err := c.Canonicalize("", link); err == nil {
+			t.Errorf("Canonicalize(%q) should fail", link)
+		}
+	}
+}
+
+// 等价网址的指纹相同
+func TestURLFilter(t *testing.T) {
+	ctx := context.Background()
+	c := NewURLFilter(ctx, NewMemoryBloomFilter(1000, 0.001), URLOption{Base: "https://example.com/list/", TrackingParams: []string{"ref"}})
+	if ok, err := c.Add(ctx, "item?b=2&a=1&ref=home"); err != nil || !ok {
+		t.Fatalf("Add = %v %v", ok, err)
+	}
+	for _, link := range []string{"https://EXAMPLE.com:443/list/item?a=1&b=2", "/list/item?a=1&b=2#x"} {
+		if ok, err := c.Exist(ctx, link); err != nil || !ok {
+			t.Errorf("Exist(%q) = %v %v", link, ok, err)
+		}
+	}
+	if ok, _ := c.ExistFrom(ctx, "https://example.com/other/", "item?a=1&b=2"); ok {
+		t.Error("different page should resolve to a different link")
+	}
+}
@@ -0,0 +1,333 @@
+// 基于配置的功能开关
+package flag
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/config"
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/aivencs/box/pkg/validate"
+)
+
+// 使用枚举限定使用时的选项
+type TypeSupport string
+type ReasonSupport string
+
+const (
+	CONFIG TypeSupport = "config"
+	// 判定依据
+	REASON_MISSING  ReasonSupport = "missing"  // 未定义
+	REASON_DISABLED ReasonSupport = "disabled" // 总开关关闭
+	REASON_ENV      ReasonSupport = "env"      // 不在目标环境
+	REASON_DENY     ReasonSupport = "deny"     // 命中黑名单
+	REASON_ALLOW    ReasonSupport = "allow"    // 命中白名单
+	REASON_ROLLOUT  ReasonSupport = "rollout"  // 按比例放量
+	// 定义默认值
+	DEFAULT_KEY     = "flags"
+	DEFAULT_ON      = "on"
+	DEFAULT_OFF     = "off"
+	DEFAULT_BUCKETS = 10000 // 放量比例精确到0.01%
+)
+
+// 定义对象
+var flag Flag
+var once sync.Once
+
+func init() {
+	ctx := context.WithValue(context.Background(), "trace", "init-for-flag")
+	validate.InitValidate(ctx, validate.VALIDATOR, validate.Option{})
+}
+
+// 抽象接口
+type Flag interface {
+	Evaluate(ctx context.Context, name string, key string) Result
+	Enabled(ctx context.Context, name string, key string) bool
+	Variant(ctx context.Context, name string, key string) string
+	All(ctx context.Context, key string) map[string]Result
+}
+
+// 初始化时所用参数
+type Option struct {
+	Conf config.Conf `json:"-" label:"配置对象" desc:"为空时使用config包初始化的配置"`
+	Key  string      `json:"key" label:"配置键名" desc:"默认为flags" default:"flags"`
+	Env  string      `json:"env" label:"环境" desc:"用于环境定向" validate:"required"`
+}
+
+// 开关定义
+// 判定顺序: 总开关 > 目标环境 > 黑名单 > 白名单 > 放量比例
+type Definition struct {
+	Enable   bool            `json:"enable" label:"总开关"`
+	Envs     []string        `json:"envs" label:"目标环境" desc:"为空时对所有环境生效"`
+	Allow    []string        `json:"allow" label:"白名单"`
+	Deny     []string        `json:"deny" label:"黑名单"`
+	Percent  *float64        `json:"percent" label:"放量比例" desc:"0-100，未设置时全量"`
+	Variants []VariantWeight `json:"variants" label:"变体" desc:"开启时按权重选取，未设置时为on"`
+	Default  string          `json:"default" label:"关闭时的变体" desc:"默认为off"`
+}
+
+// 变体及其权重
+type VariantWeight struct {
+	Name   string `json:"name" label:"名称"`
+	Weight int    `json:"weight" label:"权重"`
+}
+
+// 判定结果
+type Result struct {
+	Name    string        `json:"name" label:"开关名称"`
+	Enabled bool          `json:"enabled" label:"是否开启"`
+	Variant string        `json:"variant" label:"变体"`
+	Reason  ReasonSupport `json:"reason" label:"判定依据"`
+}
+
+// 初始化对象
+func InitFlag(ctx context.Context, support TypeSupport, option Option) error {
+	c := flag
+	var err error
+	message, err := validate.Work(ctx, option)
+	if err != nil {
+		return logger.NewError(logger.PVERROR, message, err)
+	}
+	once.Do(func() {
+		c, err = FlagFactory(ctx, support, option)
+		if err != nil {
+			return
+		}
+		if c == nil {
+			err = errors.New("初始化失败")
+		}
+		flag = c
+	})
+	return err
+}
+
+// 抽象工厂
+func FlagFactory(ctx context.Context, support TypeSupport, option Option) (Flag, error) {
+	switch support {
+	case CONFIG:
+		return NewConfigFlag(ctx, option)
+	default:
+		return NewConfigFlag(ctx, option)
+	}
+}
+
+// 读取开关定义所需的配置能力
+type source interface {
+	UnmarshalKey(key string, target interface{}) error
+	OnChange(callback config.Callback)
+}
+
+// 未指定配置对象时使用config包初始化的配置
+type global struct{}
+
+func (global) UnmarshalKey(key string, target interface{}) error {
+	return config.UnmarshalKey(key, target)
+}
+
+func (global) OnChange(callback config.Callback) {
+	config.OnChange(callback)
+}
+
+// 结构体
+// 基于配置，配置变更后自动重新加载
+type ConfigFlag struct {
+	Key         string
+	Env         string
+	kernel      source
+	definitions atomic.Value
+}
+
+// 创建基于配置的对象
+func NewConfigFlag(ctx context.Context, option Option) (Flag, error) {
+	if utf8.RuneCountInString(option.Key) == 0 {
+		option.Key = DEFAULT_KEY
+	}
+	var kernel source = global{}
+	if option.Conf != nil {
+		kernel = option.Conf
+	}
+	c := &ConfigFlag{Key: option.Key, Env: option.Env, kernel: kernel}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	kernel.OnChange(func(old, new interface{}, diff []config.Change) {
+		if err := c.load(); err != nil {
+			warn("功能开关更新未生效，沿用上一次的定义", err)
+		}
+	})
+	return c, nil
+}
+
+// 记录更新失败，日志未就绪时使用标准库输出
+func warn(text string, err error) {
+	if !logger.Initialized() {
+		log.Printf("%s: %s", text, err)
+		return
+	}
+	logger.Warn(context.WithValue(context.Background(), "trace", "flag-reload"), logger.Message{
+		Text:   text,
+		Remark: err.Error(),
+		Attr: logger.Attr{
+			Monitor: logger.Monitor{Code: logger.RWARN, Level: logger.WARN},
+		},
+	})
+}
+
+// 读取开关定义，配置中不存在时视为没有开关
+func (c *ConfigFlag) load() error {
+	definitions := map[string]Definition{}
+	if err := c.kernel.UnmarshalKey(c.Key, &definitions); err != nil {
+		if ers, ok := err.(*logger.BaseError); !ok || ers.Code() != logger.CHECK {
+			return err
+		}
+	}
+	c.definitions.Store(definitions)
+	return nil
+}
+
+// 配置的键名不区分大小写，开关名称统一按小写匹配
+func (c *ConfigFlag) Evaluate(ctx context.Context, name string, key string) Result {
+	name = strings.ToLower(name)
+	definitions, _ := c.definitions.Load().(map[string]Definition)
+	definition, ok := definitions[name]
+	if !ok {
+		return Result{Name: name, Variant: DEFAULT_OFF, Reason: REASON_MISSING}
+	}
+	return evaluate(name, definition, c.Env, key)
+}
+
+func (c *ConfigFlag) Enabled(ctx context.Context, name string, key string) bool {
+	return c.Evaluate(ctx, name, key).Enabled
+}
+
+func (c *ConfigFlag) Variant(ctx context.Context, name string, key string) string {
+	return c.Evaluate(ctx, name, key).Variant
+}
+
+// 判定所有开关
+func (c *ConfigFlag) All(ctx context.Context, key string) map[string]Result {
+	definitions, _ := c.definitions.Load().(map[string]Definition)
+	results := make(map[string]Result, len(definitions))
+	for name, definition := range definitions {
+		results[name] = evaluate(name, definition, c.Env, key)
+	}
+	return results
+}
+
+// 判定单个开关
+func evaluate(name string, definition Definition, env string, key string) Result {
+	off := Result{Name: name, Variant: definition.Default}
+	if utf8.RuneCountInString(off.Variant) == 0 {
+		off.Variant = DEFAULT_OFF
+	}
+	switch {
+	case !definition.Enable:
+		off.Reason = REASON_DISABLED
+		return off
+	case len(definition.Envs) > 0 && !contains(definition.Envs, env):
+		off.Reason = REASON_ENV
+		return off
+	case contains(definition.Deny, key):
+		off.Reason = REASON_DENY
+		return off
+	}
+	on := Result{Name: name, Enabled: true, Variant: pick(name, definition.Variants, key)}
+	if contains(definition.Allow, key) {
+		on.Reason = REASON_ALLOW
+		return on
+	}
+	on.Reason = REASON_ROLLOUT
+	off.Reason = REASON_ROLLOUT
+	if definition.Percent == nil {
+		return on
+	}
+	if float64(bucket(name, key)) < *definition.Percent*DEFAULT_BUCKETS/100 {
+		return on
+	}
+	return off
+}
+
+// 按权重选取变体，同一用户始终得到相同的变体
+func pick(name string, variants []VariantWeight, key string) string {
+	total := 0
+	for _, variant := range variants {
+		if variant.Weight > 0 {
+			total += variant.Weight
+		}
+	}
+	if total == 0 {
+		return DEFAULT_ON
+	}
+	// 与放量使用不同的散列输入，避免放量比例影响变体分布
+	point := int(hash(name+":variant:"+key) % uint32(total))
+	sorted := append([]VariantWeight(nil), variants...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	for _, variant := range sorted {
+		if variant.Weight <= 0 {
+			continue
+		}
+		if point < variant.Weight {
+			return variant.Name
+		}
+		point -= variant.Weight
+	}
+	return DEFAULT_ON
+}
+
+// 用户所在的分桶，同一开关下保持稳定
+func bucket(name string, key string) uint32 {
+	return hash(name+":"+key) % DEFAULT_BUCKETS
+}
+
+func hash(val string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(val))
+	return h.Sum32()
+}
+
+func contains(list []string, val string) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否已初始化
+func Initialized() bool {
+	return flag != nil
+}
+
+// 未初始化时视为开关未定义
+func Evaluate(ctx context.Context, name string, key string) Result {
+	if flag == nil {
+		return Result{Name: strings.ToLower(name), Variant: DEFAULT_OFF, Reason: REASON_MISSING}
+	}
+	return flag.Evaluate(ctx, name, key)
+}
+
+func Enabled(ctx context.Context, name string, key string) bool {
+	return Evaluate(ctx, name, key).Enabled
+}
+
+func Variant(ctx context.Context, name string, key string) string {
+	return Evaluate(ctx, name, key).Variant
+}
+
+// 未初始化时为空
+func All(ctx context.Context, key string) map[string]Result {
+	if flag == nil {
+		return map[string]Result{}
+	}
+	return flag.All(ctx, key)
+}
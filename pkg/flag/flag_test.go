@@ -0,0 +1,48 @@
+package flag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aivencs/box/pkg/config"
+)
+
+// 以固定定义代替配置对象
+type fixed map[string]Definition
+
+func (c fixed) UnmarshalKey(key string, target interface{}) error {
+	definitions := target.(*map[string]Definition)
+	for name, definition := range c {
+		(*definitions)[name] = definition
+	}
+	return nil
+}
+
+func (c fixed) OnChange(callback config.Callback) {}
+
+func TestEvaluateIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	// 配置读取后键名均为小写
+	c := &ConfigFlag{Env: "dev", kernel: fixed{"newcheckout": {Enable: true}}}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"newcheckout", "NewCheckout", "NEWCHECKOUT"} {
+		if res := c.Evaluate(ctx, name, "user"); !res.Enabled || res.Reason != REASON_ROLLOUT {
+			t.Errorf("Evaluate(%s) = %+v, want enabled", name, res)
+		}
+	}
+}
+
+func TestUninitialized(t *testing.T) {
+	if Initialized() {
+		t.Skip("flag already initialized")
+	}
+	ctx := context.Background()
+	if res := Evaluate(ctx, "any", "user"); res.Enabled || res.Reason != REASON_MISSING {
+		t.Errorf("Evaluate = %+v, want missing", res)
+	}
+	if Enabled(ctx, "any", "user") || Variant(ctx, "any", "user") != DEFAULT_OFF || len(All(ctx, "user")) != 0 {
+		t.Error("uninitialized flag should be off")
+	}
+}
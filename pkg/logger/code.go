@@ -19,6 +19,7 @@ const (
 	DVERROR     Code = 10009 // 数据结果未通过校验 error
 	RWARN       Code = 10010 // 运行时发生异常 warn
 	RPWARN      Code = 10011 // 运行时发生错误 error
+	CONFCHANGE  Code = 10012 // 配置变更 info
 	CALLTIMEOUT Code = 20001 // 调用超时 error
 	CALLERROR   Code = 20002 // 调用错误 error
 	INTERRUPT   Code = 30001 // 组件中断 fatal
@@ -41,6 +42,7 @@ func init() {
 		DVERROR:     {code: DVERROR, level: ERROR, label: "数据结果未通过校验"},
 		RWARN:       {code: RWARN, level: WARN, label: "运行时发生异常"},
 		RPWARN:      {code: RPWARN, level: WARN, label: "运行时发生错误"},
+		CONFCHANGE:  {code: CONFCHANGE, level: INFO, label: "配置变更"},
 		CALLTIMEOUT: {code: CALLTIMEOUT, level: ERROR, label: "调用超时"},
 		CALLERROR:   {code: CALLERROR, level: ERROR, label: "调用错误"},
 		INTERRUPT:   {code: INTERRUPT, level: FATAL, label: "组件中断"},
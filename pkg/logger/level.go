@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 可在运行时调整日志级别
+type Leveler interface {
+	SetLevel(level LevelSupport) error
+	GetLevel() LevelSupport
+	SetLabelLevel(label string, level LevelSupport) error
+	RemoveLabelLevel(label string)
+	LabelLevels() map[string]LevelSupport
+}
+
+// 日志级别及按别称覆盖的级别
+// 输出端以全局与覆盖级别中的最低者放行，再按日志的别称逐条判断
+type levels struct {
+	global zap.AtomicLevel
+	floor  zap.AtomicLevel
+	guard  sync.RWMutex
+	labels map[string]zapcore.Level
+}
+
+func newLevels(level zapcore.Level) *levels {
+	return &levels{
+		global: zap.NewAtomicLevelAt(level),
+		floor:  zap.NewAtomicLevelAt(level),
+		labels: map[string]zapcore.Level{},
+	}
+}
+
+// 转换为ZAP的级别
+func parseLevel(level LevelSupport) (zapcore.Level, error) {
+	switch level {
+	case DEBUG:
+		return zapcore.DebugLevel, nil
+	case INFO, "":
+		return zapcore.InfoLevel, nil
+	case WARN:
+		return zapcore.WarnLevel, nil
+	case ERROR:
+		return zapcore.ErrorLevel, nil
+	case FATAL:
+		return zapcore.FatalLevel, nil
+	default:
+		return zapcore.InfoLevel, NewError(PVERROR, fmt.Sprintf("日志级别%s有误", level), nil)
+	}
+}
+
+// 指定别称的日志是否输出
+func (c *levels) enabled(label string, level zapcore.Level) bool {
+	c.guard.RLock()
+	min, ok := c.labels[label]
+	c.guard.RUnlock()
+	if !ok {
+		min = c.global.Level()
+	}
+	return level >= min
+}
+
+// 重新计算输出端的放行级别，调用方需持有写锁
+func (c *levels) refresh() {
+	min := c.global.Level()
+	for _, level := range c.labels {
+		if level < min {
+			min = level
+		}
+	}
+	c.floor.SetLevel(min)
+}
+
+func (c *levels) SetLevel(level LevelSupport) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	c.guard.Lock()
+	defer c.guard.Unlock()
+	c.global.SetLevel(l)
+	c.refresh()
+	return nil
+}
+
+func (c *levels) GetLevel() LevelSupport {
+	return LevelSupport(c.global.Level().String())
+}
+
+func (c *levels) SetLabelLevel(label string, level LevelSupport) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	c.guard.Lock()
+	defer c.guard.Unlock()
+	c.labels[label] = l
+	c.refresh()
+	return nil
+}
+
+func (c *levels) RemoveLabelLevel(label string) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+	delete(c.labels, label)
+	c.refresh()
+}
+
+func (c *levels) LabelLevels() map[string]LevelSupport {
+	c.guard.RLock()
+	defer c.guard.RUnlock()
+	result := make(map[string]LevelSupport, len(c.labels))
+	for label, level := range c.labels {
+		result[label] = LevelSupport(level.String())
+	}
+	return result
+}
+
+// 调整日志级别的请求与响应
+// label为空时调整全局级别，此时level必填；level为空时移除该别称的覆盖
+type LevelPayload struct {
+	Level  LevelSupport            `json:"level" label:"级别"`
+	Label  string                  `json:"label,omitempty" label:"别称"`
+	Labels map[string]LevelSupport `json:"labels,omitempty" label:"按别称覆盖的级别"`
+}
+
+// 查看与调整日志级别的HTTP接口
+// GET返回当前级别，PUT或POST调整级别，可通过echo.WrapHandler挂载
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := logger.(Leveler)
+		if !ok {
+			reply(w, http.StatusServiceUnavailable, map[string]string{"message": "日志未初始化或不支持调整级别"})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var payload LevelPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				reply(w, http.StatusBadRequest, map[string]string{"message": "请求格式有误"})
+				return
+			}
+			var err error
+			switch {
+			case payload.Label == "" && payload.Level == "":
+				err = NewError(PVERROR, "未指定日志级别", nil)
+			case payload.Label == "":
+				err = c.SetLevel(payload.Level)
+			case payload.Level == "":
+				c.RemoveLabelLevel(payload.Label)
+			default:
+				err = c.SetLabelLevel(payload.Label, payload.Level)
+			}
+			if err != nil {
+				reply(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+				return
+			}
+		default:
+			reply(w, http.StatusMethodNotAllowed, map[string]string{"message": "不支持的请求方式"})
+			return
+		}
+		reply(w, http.StatusOK, LevelPayload{Level: c.GetLevel(), Labels: c.LabelLevels()})
+	})
+}
+
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// 调整全局日志级别
+func SetLevel(level LevelSupport) error {
+	c, ok := logger.(Leveler)
+	if !ok {
+		return NewError(RPERROR, "日志未初始化或不支持调整级别", nil)
+	}
+	return c.SetLevel(level)
+}
+
+// 当前全局日志级别
+func GetLevel() LevelSupport {
+	if c, ok := logger.(Leveler); ok {
+		return c.GetLevel()
+	}
+	return DEFAULT_LEVEL
+}
+
+// 调整指定别称的日志级别
+func SetLabelLevel(label string, level LevelSupport) error {
+	c, ok := logger.(Leveler)
+	if !ok {
+		return NewError(RPERROR, "日志未初始化或不支持调整级别", nil)
+	}
+	return c.SetLabelLevel(label, level)
+}
+
+// 移除指定别称的日志级别
+func RemoveLabelLevel(label string) {
+	if c, ok := logger.(Leveler); ok {
+		c.RemoveLabelLevel(label)
+	}
+}
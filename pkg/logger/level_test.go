@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelsEnabled(t *testing.T) {
+	c := newLevels(zapcore.InfoLevel)
+	if err := c.SetLabelLevel("db", DEBUG); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetLabelLevel("http", ERROR); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		label string
+		level zapcore.Level
+		want  bool
+	}{
+		{label: "", level: zapcore.DebugLevel, want: false},
+		{label: "", level: zapcore.InfoLevel, want: true},
+		{label: "db", level: zapcore.DebugLevel, want: true},
+		{label: "http", level: zapcore.WarnLevel, want: false},
+		{label: "http", level: zapcore.ErrorLevel, want: true},
+	}
+	for _, tt := range tests {
+		if got := c.enabled(tt.label, tt.level); got != tt.want {
+			t.Errorf("enabled(%q, %s) = %v, want %v", tt.label, tt.level, got, tt.want)
+		}
+	}
+	// 输出端按最低的覆盖级别放行
+	if !c.floor.Enabled(zapcore.DebugLevel) {
+		t.Error("floor should follow the lowest label level")
+	}
+	c.RemoveLabelLevel("db")
+	if c.floor.Enabled(zapcore.DebugLevel) || c.enabled("db", zapcore.DebugLevel) {
+		t.Error("removed label should fall back to global level")
+	}
+	if err := c.SetLevel("trace"); err == nil {
+		t.Error("SetLevel(trace) should fail")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	saved := logger
+	defer func() { logger = saved }()
+	logger = NewZapLogger(context.Background(), Option{Application: "app", Env: "dev", Label: "test", DisableStdout: true})
+	srv := httptest.NewServer(LevelHandler())
+	defer srv.Close()
+	tests := []struct {
+		method string
+		body   string
+		status int
+		level  LevelSupport
+		labels map[string]LevelSupport
+	}{
+		{method: http.MethodGet, status: http.StatusOK, level: INFO},
+		{method: http.MethodPut, body: `{"level":"warn"}`, status: http.StatusOK, level: WARN},
+		{method: http.MethodPut, body: `{"label":"db","level":"debug"}`, status: http.StatusOK, level: WARN, labels: map[string]LevelSupport{"db": DEBUG}},
+		// 未指定别称时级别必填，不可回退为默认级别
+		{method: http.MethodPut, body: `{}`, status: http.StatusBadRequest},
+		{method: http.MethodPut, body: `{"level":"trace"}`, status: http.StatusBadRequest},
+		{method: http.MethodPut, body: `level`, status: http.StatusBadRequest},
+		{method: http.MethodPost, body: `{"label":"db"}`, status: http.StatusOK, level: WARN},
+		{method: http.MethodDelete, status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader(tt.body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload LevelPayload
+		json.NewDecoder(res.Body).Decode(&payload)
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.body, res.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if payload.Level != tt.level || len(payload.Labels) != len(tt.labels) {
+			t.Errorf("%s %s: payload = %+v", tt.method, tt.body, payload)
+		}
+		for label, level := range tt.labels {
+			if payload.Labels[label] != level {
+				t.Errorf("%s %s: labels = %v", tt.method, tt.body, payload.Labels)
+			}
+		}
+	}
+	if GetLevel() != WARN {
+		t.Errorf("GetLevel = %s, want warn", GetLevel())
+	}
+}
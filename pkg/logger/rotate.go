@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 使用枚举限定按时间切分的周期
+type RotateSupport string
+
+const (
+	ROTATE_NONE RotateSupport = ""
+	ROTATE_HOUR RotateSupport = "hour"
+	ROTATE_DAY  RotateSupport = "day"
+	// 定义默认值
+	DEFAULT_MAX_SIZE      = 100 // 单个文件的默认上限，单位MB
+	DEFAULT_BACKUP_LAYOUT = "2006-01-02T15-04-05.000"
+	DEFAULT_COMPRESS_EXT  = ".gz"
+)
+
+// 日志文件参数
+type FileOption struct {
+	Path       string        `json:"path" label:"文件路径" desc:"填写时输出到文件，例如/var/log/app/app.log"`
+	MaxSize    int           `json:"max_size" label:"单个文件上限" desc:"单位MB，超过后切分" default:"100"`
+	Rotate     RotateSupport `json:"rotate" label:"按时间切分" desc:"hour或day，默认不按时间切分" validate:"omitempty,oneof=hour day"`
+	MaxBackups int           `json:"max_backups" label:"保留的文件数量" desc:"默认全部保留"`
+	MaxAge     int           `json:"max_age" label:"保留天数" desc:"默认全部保留"`
+	Compress   bool          `json:"compress" label:"是否压缩" desc:"切分后的文件以gzip压缩"`
+	SplitLevel bool          `json:"split_level" label:"是否按级别分文件" desc:"例如app.log拆分为app.info.log、app.error.log等"`
+}
+
+// 可切分的日志文件
+// 切分时持有锁，期间的写入等待切分完成后写入新文件，不会丢失
+type RotateWriter struct {
+	option   FileOption
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	deadline time.Time
+	tidy     chan struct{}
+	closed   bool
+}
+
+// 创建可切分的日志文件
+func NewRotateWriter(option FileOption) (*RotateWriter, error) {
+	return newRotateWriter(option, false)
+}
+
+// lazy为true时首次写入才创建文件，用于可能不输出的级别
+func newRotateWriter(option FileOption, lazy bool) (*RotateWriter, error) {
+	if option.MaxSize == 0 {
+		option.MaxSize = DEFAULT_MAX_SIZE
+	}
+	if err := os.MkdirAll(filepath.Dir(option.Path), 0755); err != nil {
+		return nil, err
+	}
+	c := &RotateWriter{option: option, tidy: make(chan struct{}, 1)}
+	if !lazy {
+		if err := c.open(); err != nil {
+			return nil, err
+		}
+	}
+	go c.clean()
+	c.schedule()
+	return c, nil
+}
+
+func (c *RotateWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	if c.file == nil {
+		if err := c.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := time.Now()
+	limit := int64(c.option.MaxSize) * 1024 * 1024
+	switch {
+	case c.expired(now) && c.size == 0:
+		// 空文件无需切分，进入下一个周期即可
+		c.deadline = c.next(c.period(now))
+	case c.expired(now), c.size > 0 && c.size+int64(len(p)) > limit:
+		c.rotate()
+	}
+	if c.file == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := c.file.Write(p)
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *RotateWriter) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Sync()
+}
+
+// 关闭文件并停止后台清理，之后的写入返回os.ErrClosed
+func (c *RotateWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.tidy)
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// 打开当前文件，已有文件属于上一个周期时先切分
+func (c *RotateWriter) open() error {
+	now := time.Now()
+	if info, err := os.Stat(c.option.Path); err == nil {
+		if start := c.period(now); !start.IsZero() && info.ModTime().Before(start) {
+			os.Rename(c.option.Path, c.backup(info.ModTime()))
+		}
+	}
+	file, err := os.OpenFile(c.option.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	c.file = file
+	c.size = info.Size()
+	if start := c.period(now); !start.IsZero() {
+		c.deadline = c.next(start)
+	}
+	return nil
+}
+
+// 切分当前文件，调用方需持有锁
+// 重命名失败时继续写入原文件，再写满一个上限后重试，避免每次写入都重新打开
+func (c *RotateWriter) rotate() {
+	c.file.Close()
+	c.file = nil
+	if err := os.Rename(c.option.Path, c.backup(time.Now())); err != nil {
+		if c.open() == nil {
+			c.size = 0
+		}
+		return
+	}
+	c.open()
+	c.schedule()
+}
+
+func (c *RotateWriter) expired(now time.Time) bool {
+	return !c.deadline.IsZero() && !now.Before(c.deadline)
+}
+
+// 当前周期的开始时间，不按时间切分时为零值
+func (c *RotateWriter) period(now time.Time) time.Time {
+	switch c.option.Rotate {
+	case ROTATE_HOUR:
+		return now.Truncate(time.Hour)
+	case ROTATE_DAY:
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+func (c *RotateWriter) next(start time.Time) time.Time {
+	if c.option.Rotate == ROTATE_HOUR {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// 切分后的文件名，例如app-2006-01-02T15-04-05.000.log
+// 同名文件已存在时顺延一毫秒
+func (c *RotateWriter) backup(t time.Time) string {
+	prefix, ext := c.split()
+	for {
+		path := prefix + t.Format(DEFAULT_BACKUP_LAYOUT) + ext
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(path + DEFAULT_COMPRESS_EXT); os.IsNotExist(err) {
+				return path
+			}
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func (c *RotateWriter) split() (string, string) {
+	ext := filepath.Ext(c.option.Path)
+	return strings.TrimSuffix(c.option.Path, ext) + "-", ext
+}
+
+// 通知后台压缩与清理，调用方需持有锁或尚未返回对象
+func (c *RotateWriter) schedule() {
+	if c.closed {
+		return
+	}
+	select {
+	case c.tidy <- struct{}{}:
+	default:
+	}
+}
+
+// 在后台压缩与清理切分后的文件，不阻塞写入
+func (c *RotateWriter) clean() {
+	for range c.tidy {
+		backups := c.backups()
+		now := time.Now()
+		for i, item := range backups {
+			expired := c.option.MaxAge > 0 && now.Sub(item.when) > time.Duration(c.option.MaxAge)*24*time.Hour
+			if (c.option.MaxBackups > 0 && i >= c.option.MaxBackups) || expired {
+				os.Remove(item.path)
+				continue
+			}
+			if c.option.Compress && !strings.HasSuffix(item.path, DEFAULT_COMPRESS_EXT) {
+				compress(item.path)
+			}
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	when time.Time
+}
+
+// 切分后的文件，按时间倒序
+func (c *RotateWriter) backups() []backupFile {
+	prefix, ext := c.split()
+	matches, _ := filepath.Glob(prefix + "*")
+	result := []backupFile{}
+	for _, path := range matches {
+		stamp := strings.TrimPrefix(path, prefix)
+		stamp = strings.TrimSuffix(stamp, DEFAULT_COMPRESS_EXT)
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		when, err := time.ParseInLocation(DEFAULT_BACKUP_LAYOUT, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		result = append(result, backupFile{path: path, when: when})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].when.After(result[j].when)
+	})
+	return result
+}
+
+// 以gzip压缩文件，成功后删除原文件
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + DEFAULT_COMPRESS_EXT + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+DEFAULT_COMPRESS_EXT); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 等待后台清理完成
+func eventually(t *testing.T, ok func() bool, text string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatal(text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// 使当前周期立即到期
+func expire(c *RotateWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = time.Now().Add(-time.Second)
+}
+
+func TestRotateSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	c, err := NewRotateWriter(FileOption{Path: path, MaxSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	chunk := bytes.Repeat([]byte("a"), 600*1024)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := c.backups()
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want 1", backups)
+	}
+	for _, path := range []string{path, backups[0].path} {
+		if info, err := os.Stat(path); err != nil || info.Size() != int64(len(chunk)) {
+			t.Errorf("%s: size = %v, want %d", path, info, len(chunk))
+		}
+	}
+}
+
+func TestRotateTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	c, err := NewRotateWriter(FileOption{Path: path, Rotate: ROTATE_HOUR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// 空文件到期后不切分
+	expire(c)
+	c.Write([]byte("first\n"))
+	if backups := c.backups(); len(backups) != 0 {
+		t.Fatalf("backups = %v, want none", backups)
+	}
+	expire(c)
+	c.Write([]byte("second\n"))
+	backups := c.backups()
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want 1", backups)
+	}
+	if content, _ := os.ReadFile(backups[0].path); string(content) != "first\n" {
+		t.Errorf("backup = %q", content)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "second\n" {
+		t.Errorf("current = %q", content)
+	}
+	if !c.deadline.After(time.Now()) {
+		t.Errorf("deadline = %s, want next hour", c.deadline)
+	}
+}
+
+func TestRotateRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Now()
+	names := []string{}
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 72 * time.Hour} {
+		name := filepath.Join(dir, "app-"+now.Add(-age).Format(DEFAULT_BACKUP_LAYOUT)+".log")
+		if err := os.WriteFile(name, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	// 与切分文件无关的文件不受影响
+	other := filepath.Join(dir, "app-other.log")
+	os.WriteFile(other, nil, 0644)
+	tests := []struct {
+		option FileOption
+		kept   int
+	}{
+		{option: FileOption{MaxAge: 1}, kept: 3},
+		{option: FileOption{MaxBackups: 2}, kept: 2},
+	}
+	for _, tt := range tests {
+		option := tt.option
+		option.Path = path
+		c, err := NewRotateWriter(option)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, func() bool { return !exists(names[tt.kept]) }, "expired backup not removed")
+		c.Close()
+		for _, name := range names[:tt.kept] {
+			if !exists(name) {
+				t.Errorf("%+v: %s removed", tt.option, name)
+			}
+		}
+	}
+	if !exists(other) {
+		t.Error("unrelated file removed")
+	}
+}
+
+func TestRotateCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	c, err := NewRotateWriter(FileOption{Path: path, Rotate: ROTATE_DAY, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Write([]byte("first\n"))
+	expire(c)
+	c.Write([]byte("second\n"))
+	var backups []backupFile
+	eventually(t, func() bool {
+		backups = c.backups()
+		return len(backups) == 1 && filepath.Ext(backups[0].path) == DEFAULT_COMPRESS_EXT
+	}, "backup not compressed")
+	file, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := io.ReadAll(zr); string(content) != "first\n" {
+		t.Errorf("backup = %q", content)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+// 关闭后不再创建文件
+func TestRotateClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	c, err := NewRotateWriter(FileOption{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(path)
+	if _, err := c.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close = %v, want os.ErrClosed", err)
+	}
+	if exists(path) {
+		t.Error("file reopened after Close")
+	}
+}
+
+// 按级别分文件时，低于当前级别的文件在首次写入时创建
+func TestSplitLevelLazy(t *testing.T) {
+	dir := t.TempDir()
+	option := Option{Application: "app", Env: "dev", Label: "test", DisableStdout: true, File: FileOption{Path: filepath.Join(dir, "app.log"), SplitLevel: true}}
+	c := NewZapLogger(context.Background(), option).(*ZapLogger)
+	defer c.Close()
+	debug, info := filepath.Join(dir, "app.debug.log"), filepath.Join(dir, "app.info.log")
+	if exists(debug) || !exists(info) {
+		t.Fatal("debug file should be created on demand")
+	}
+	ctx := context.WithValue(context.Background(), "trace", "test")
+	c.Debug(ctx, Message{Text: "hidden"})
+	if exists(debug) {
+		t.Fatal("debug file created below level")
+	}
+	c.SetLevel(DEBUG)
+	c.Debug(ctx, Message{Text: "shown"})
+	if content, _ := os.ReadFile(debug); !bytes.Contains(content, []byte("shown")) || bytes.Contains(content, []byte("hidden")) {
+		t.Errorf("debug file = %q", content)
+	}
+}
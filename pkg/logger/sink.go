@@ -0,0 +1,451 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// 使用枚举限定输出端类型
+type SinkSupport string
+
+const (
+	SINK_STDOUT SinkSupport = "stdout"
+	SINK_FILE   SinkSupport = "file"
+	SINK_SYSLOG SinkSupport = "syslog"
+	SINK_HTTP   SinkSupport = "http"
+	// 定义默认值
+	DEFAULT_SINK_QUEUE     = 1024                               // 异步输出端的队列长度，队列满时丢弃
+	DEFAULT_SINK_TIMEOUT   = 5 * time.Second                    // 网络输出端的超时时间
+	DEFAULT_SINK_RETRY     = time.Second                        // 网络输出端连接失败后的重试间隔
+	DEFAULT_BATCH_SIZE     = 100                                // HTTP输出端每批的条数
+	DEFAULT_FLUSH_INTERVAL = 1000                               // HTTP输出端的发送间隔，单位毫秒
+	DEFAULT_FACILITY       = 16                                 // syslog默认使用local0
+	DEFAULT_SYNC_TIMEOUT   = time.Second                        // 异步输出端Sync与Close等待写出的最长时间
+	DEFAULT_SYSLOG_LAYOUT  = "2006-01-02T15:04:05.000000Z07:00" // syslog的时间戳格式，精确到微秒
+	DEFAULT_APP_NAME_SIZE  = 48                                 // syslog的APP-NAME长度上限
+	DEFAULT_HOSTNAME_SIZE  = 255                                // syslog的HOSTNAME长度上限
+)
+
+// syslog报文头共用的缓冲池
+var syslogPool = buffer.NewPool()
+
+// 输出端参数
+type SinkOption struct {
+	Type          SinkSupport       `json:"type" label:"类型" desc:"stdout、file、syslog或http" validate:"required,oneof=stdout file syslog http"`
+	Encode        EncoderSupport    `json:"encoder" label:"输出格式" desc:"默认为json"`
+	Level         LevelSupport      `json:"level" label:"最低级别" desc:"与全局级别同时生效" validate:"omitempty,oneof=debug info warn error fatal"`
+	File          FileOption        `json:"file" label:"日志文件" desc:"用于file"`
+	Network       string            `json:"network" label:"网络类型" desc:"用于syslog，udp、tcp或unix" validate:"omitempty,oneof=udp tcp unix"`
+	Address       string            `json:"address" label:"地址" desc:"syslog为host:port或socket路径，http为完整网址"`
+	Facility      int               `json:"facility" label:"syslog设施" desc:"默认为16，即local0" validate:"min=0,max=23"`
+	Headers       map[string]string `json:"headers" label:"请求头" desc:"用于http"`
+	BatchSize     int               `json:"batch_size" label:"每批条数" desc:"用于http" default:"100"`
+	FlushInterval int               `json:"flush_interval" label:"发送间隔" desc:"用于http，单位毫秒" default:"1000"`
+	QueueSize     int               `json:"queue_size" label:"队列长度" desc:"用于syslog与http，队列满时丢弃" default:"1024"`
+}
+
+// 按参数构建输出端
+// 单个输出端不可用时跳过并提示，其余输出端照常工作
+func sinkCores(config EncoderConfig, sinks []SinkOption, floor zapcore.LevelEnabler, application string) ([]zapcore.Core, []io.Closer) {
+	cores := []zapcore.Core{}
+	closers := []io.Closer{}
+	for _, sink := range sinks {
+		min, _ := parseLevel(sink.Level)
+		if sink.Level == "" {
+			min = zapcore.DebugLevel
+		}
+		enabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= min && floor.Enabled(l)
+		})
+		encoder := applyEncoder(sink.Encode, config)
+		switch sink.Type {
+		case SINK_STDOUT:
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), enabler))
+		case SINK_FILE:
+			files, writers, err := fileCores(encoder, sink.File, enabler)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "日志文件不可用: %s\n", err)
+			}
+			cores = append(cores, files...)
+			closers = append(closers, writers...)
+		case SINK_SYSLOG:
+			writer := NewSyslogWriter(sink)
+			cores = append(cores, zapcore.NewCore(newSyslogEncoder(encoder, sink.Facility, application), writer, enabler))
+			closers = append(closers, writer)
+		case SINK_HTTP:
+			writer := NewHTTPWriter(sink)
+			cores = append(cores, zapcore.NewCore(encoder, writer, enabler))
+			closers = append(closers, writer)
+		}
+	}
+	return cores, closers
+}
+
+// 异步队列
+// 写入时复制内容后立即返回，队列满时丢弃并计数，避免拖慢其他输出端
+// Sync与Close最多等待DEFAULT_SYNC_TIMEOUT，Close超时后中断进行中的发送
+type queue struct {
+	items   chan []byte
+	flushes chan chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	once    sync.Once
+	dropped uint64
+}
+
+func newQueue(size int) *queue {
+	if size <= 0 {
+		size = DEFAULT_SINK_QUEUE
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &queue{
+		items:   make(chan []byte, size),
+		flushes: make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (c *queue) Write(p []byte) (int, error) {
+	select {
+	case <-c.closing:
+		atomic.AddUint64(&c.dropped, 1)
+		return len(p), nil
+	default:
+	}
+	item := append([]byte(nil), p...)
+	select {
+	case c.items <- item:
+	default:
+		atomic.AddUint64(&c.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// 等待此前写入的内容写出
+func (c *queue) Sync() error {
+	timer := time.NewTimer(DEFAULT_SYNC_TIMEOUT)
+	defer timer.Stop()
+	flushed := make(chan struct{})
+	select {
+	case c.flushes <- flushed:
+	case <-c.done:
+		return nil
+	case <-timer.C:
+		return NewError(TIMEOUT, "日志输出端写出超时", nil)
+	}
+	select {
+	case <-flushed:
+	case <-c.done:
+	case <-timer.C:
+		return NewError(TIMEOUT, "日志输出端写出超时", nil)
+	}
+	return nil
+}
+
+// 写出剩余内容后停止后台发送，之后的写入丢弃
+func (c *queue) Close() error {
+	c.once.Do(func() {
+		close(c.closing)
+	})
+	timer := time.NewTimer(DEFAULT_SYNC_TIMEOUT)
+	defer timer.Stop()
+	defer c.cancel()
+	select {
+	case <-c.done:
+		return nil
+	case <-timer.C:
+		return NewError(TIMEOUT, "日志输出端关闭超时", nil)
+	}
+}
+
+// 已丢弃的条数
+func (c *queue) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// syslog输出端
+// 每条日志一个报文，tcp按RFC 6587以长度前缀分帧，连接断开后自动重连
+type SyslogWriter struct {
+	*queue
+	Network string
+	Address string
+	conn    net.Conn
+}
+
+// 创建syslog输出端
+func NewSyslogWriter(option SinkOption) *SyslogWriter {
+	if option.Network == "" {
+		option.Network = "udp"
+	}
+	c := &SyslogWriter{queue: newQueue(option.QueueSize), Network: option.Network, Address: option.Address}
+	go c.work()
+	return c
+}
+
+func (c *SyslogWriter) work() {
+	defer close(c.done)
+	defer c.close()
+	for {
+		select {
+		case item := <-c.items:
+			c.deliver(item)
+		case flushed := <-c.flushes:
+			c.drain()
+			close(flushed)
+		case <-c.closing:
+			c.drain()
+			return
+		}
+	}
+}
+
+// 发送队列中已有的日志
+func (c *SyslogWriter) drain() {
+	for {
+		select {
+		case item := <-c.items:
+			c.deliver(item)
+		default:
+			return
+		}
+	}
+}
+
+func (c *SyslogWriter) deliver(item []byte) {
+	if c.ctx.Err() != nil {
+		atomic.AddUint64(&c.dropped, 1)
+		return
+	}
+	if err := c.send(item); err != nil {
+		// 重连一次，仍失败时丢弃并等待后再处理后续日志
+		c.close()
+		if err := c.send(item); err != nil {
+			c.close()
+			atomic.AddUint64(&c.dropped, 1)
+			select {
+			case <-time.After(DEFAULT_SINK_RETRY):
+			case <-c.ctx.Done():
+			}
+		}
+	}
+}
+
+func (c *SyslogWriter) send(item []byte) error {
+	if c.conn == nil {
+		conn, err := c.dial()
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	if c.Network == "tcp" {
+		item = append([]byte(strconv.Itoa(len(item))+" "), item...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(DEFAULT_SINK_TIMEOUT))
+	_, err := c.conn.Write(item)
+	return err
+}
+
+// unix优先使用数据报，与/dev/log一致
+func (c *SyslogWriter) dial() (net.Conn, error) {
+	if c.Network == "unix" {
+		if conn, err := net.DialTimeout("unixgram", c.Address, DEFAULT_SINK_TIMEOUT); err == nil {
+			return conn, nil
+		}
+	}
+	return net.DialTimeout(c.Network, c.Address, DEFAULT_SINK_TIMEOUT)
+}
+
+func (c *SyslogWriter) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// RFC 5424格式的编码器
+// 在原编码结果前加入报文头，PRI按日志级别计算
+type syslogEncoder struct {
+	Encoder
+	facility    int
+	hostname    string
+	application string
+}
+
+func newSyslogEncoder(encoder Encoder, facility int, application string) Encoder {
+	if facility == 0 {
+		facility = DEFAULT_FACILITY
+	}
+	hostname, _ := os.Hostname()
+	return &syslogEncoder{Encoder: encoder, facility: facility, hostname: printable(hostname, DEFAULT_HOSTNAME_SIZE), application: printable(application, DEFAULT_APP_NAME_SIZE)}
+}
+
+// 转为RFC 5424报文头可用的可打印ASCII字符，其余字符替换为下划线，为空时使用-
+func printable(text string, size int) string {
+	var b strings.Builder
+	for _, r := range text {
+		if b.Len() >= size {
+			break
+		}
+		if r < '!' || r > '~' {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+func (c *syslogEncoder) Clone() Encoder {
+	return &syslogEncoder{Encoder: c.Encoder.Clone(), facility: c.facility, hostname: c.hostname, application: c.application}
+}
+
+func (c *syslogEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	body, err := c.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Free()
+	out := syslogPool.Get()
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fmt.Fprintf(out, "<%d>1 %s %s %s %d - - ", c.facility*8+severity(entry.Level),
+		entry.Time.Format(DEFAULT_SYSLOG_LAYOUT), c.hostname, c.application, os.Getpid())
+	out.Write(bytes.TrimRight(body.Bytes(), "\n"))
+	return out, nil
+}
+
+// 日志级别对应的syslog严重程度
+func severity(level zapcore.Level) int {
+	switch {
+	case level >= zapcore.FatalLevel:
+		return 2
+	case level >= zapcore.ErrorLevel:
+		return 3
+	case level == zapcore.WarnLevel:
+		return 4
+	case level == zapcore.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// HTTP输出端
+// 按条数或时间间隔批量发送，请求体为逐行的日志，发送失败时丢弃该批
+type HTTPWriter struct {
+	*queue
+	Address  string
+	Headers  map[string]string
+	Client   *http.Client
+	size     int
+	interval time.Duration
+}
+
+// 创建HTTP输出端
+func NewHTTPWriter(option SinkOption) *HTTPWriter {
+	if option.BatchSize <= 0 {
+		option.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if option.FlushInterval <= 0 {
+		option.FlushInterval = DEFAULT_FLUSH_INTERVAL
+	}
+	c := &HTTPWriter{
+		queue:    newQueue(option.QueueSize),
+		Address:  option.Address,
+		Headers:  option.Headers,
+		Client:   &http.Client{Timeout: DEFAULT_SINK_TIMEOUT},
+		size:     option.BatchSize,
+		interval: time.Duration(option.FlushInterval) * time.Millisecond,
+	}
+	go c.work()
+	return c
+}
+
+func (c *HTTPWriter) work() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	batch := [][]byte{}
+	for {
+		select {
+		case item := <-c.items:
+			batch = append(batch, item)
+			if len(batch) < c.size {
+				continue
+			}
+		case <-ticker.C:
+		case flushed := <-c.flushes:
+			c.post(c.drain(batch))
+			batch = [][]byte{}
+			close(flushed)
+			continue
+		case <-c.closing:
+			c.post(c.drain(batch))
+			return
+		}
+		c.post(batch)
+		batch = [][]byte{}
+	}
+}
+
+// 按批发送队列中已有的日志，返回不足一批的剩余部分
+func (c *HTTPWriter) drain(batch [][]byte) [][]byte {
+	for {
+		select {
+		case item := <-c.items:
+			batch = append(batch, item)
+			if len(batch) >= c.size {
+				c.post(batch)
+				batch = [][]byte{}
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+func (c *HTTPWriter) post(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	body := bytes.Join(batch, nil)
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.Address, bytes.NewReader(body))
+	if err != nil {
+		atomic.AddUint64(&c.dropped, uint64(len(batch)))
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for key, val := range c.Headers {
+		req.Header.Set(key, val)
+	}
+	res, err := c.Client.Do(req)
+	if err != nil {
+		atomic.AddUint64(&c.dropped, uint64(len(batch)))
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		atomic.AddUint64(&c.dropped, uint64(len(batch)))
+	}
+}
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - - MSG
+var syslogHeader = regexp.MustCompile(`^<(\d+)>1 (\S+) (\S+) (\S+) (\d+) - - (.*)$`)
+
+// 解析报文头，返回PRI、APP-NAME与消息
+func parseSyslog(t *testing.T, frame string) (int, string, string) {
+	t.Helper()
+	match := syslogHeader.FindStringSubmatch(frame)
+	if match == nil {
+		t.Fatalf("frame = %q, want RFC 5424", frame)
+	}
+	if _, err := time.Parse(DEFAULT_SYSLOG_LAYOUT, match[2]); err != nil || len(match[2]) < len("2006-01-02T15:04:05.000000Z") {
+		t.Errorf("timestamp = %q", match[2])
+	}
+	pri, _ := strconv.Atoi(match[1])
+	return pri, match[4], match[6]
+}
+
+func newSinkLogger(application string, sinks ...SinkOption) *ZapLogger {
+	option := Option{Application: application, Env: "dev", Label: "test", Level: DEBUG, Sinks: sinks}
+	return NewZapLogger(context.Background(), option).(*ZapLogger)
+}
+
+var testCtx = context.WithValue(context.Background(), "trace", "test")
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	application := "order service\x01" + strings.Repeat("x", 60)
+	c := newSinkLogger(application, SinkOption{Type: SINK_SYSLOG, Network: "udp", Address: conn.LocalAddr().String(), Facility: 1})
+	c.Warn(testCtx, Message{Text: "first"})
+	c.Debug(testCtx, Message{Text: "second"})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pri  int
+		text string
+	}{
+		{pri: 1*8 + 4, text: "first"},
+		{pri: 1*8 + 7, text: "second"},
+	}
+	buf := make([]byte, 64*1024)
+	for _, tt := range tests {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pri, app, msg := parseSyslog(t, string(buf[:n]))
+		if pri != tt.pri || !strings.Contains(msg, tt.text) {
+			t.Errorf("pri = %d msg = %q, want %d %q", pri, msg, tt.pri, tt.text)
+		}
+		if want := ("order_service_" + strings.Repeat("x", 60))[:DEFAULT_APP_NAME_SIZE]; app != want {
+			t.Errorf("app = %q, want %q", app, want)
+		}
+	}
+}
+
+// tcp以长度前缀分帧
+func TestSyslogTCP(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	frames := make(chan string, 10)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+			if err != nil {
+				frames <- "bad length " + size
+				return
+			}
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return
+			}
+			frames <- string(frame)
+		}
+	}()
+	c := newSinkLogger("app", SinkOption{Type: SINK_SYSLOG, Network: "tcp", Address: lis.Addr().String()})
+	defer c.Close()
+	c.Error(testCtx, Message{Text: "line one\nline two"})
+	c.Info(testCtx, Message{Text: "third"})
+	if err := c.Kernel.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pri  int
+		text string
+	}{
+		{pri: DEFAULT_FACILITY*8 + 3, text: `line one\nline two`},
+		{pri: DEFAULT_FACILITY*8 + 6, text: "third"},
+	}
+	for _, tt := range tests {
+		select {
+		case frame := <-frames:
+			pri, app, msg := parseSyslog(t, frame)
+			if pri != tt.pri || app != "app" || !strings.Contains(msg, tt.text) {
+				t.Errorf("frame = %q, want pri %d with %q", frame, tt.pri, tt.text)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no frame received")
+		}
+	}
+}
+
+// 收集HTTP输出端的请求，每个请求记录日志条数
+type collector struct {
+	mu      sync.Mutex
+	batches []int
+	stall   chan struct{}
+}
+
+func (f *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.stall != nil {
+		select {
+		case <-f.stall:
+		case <-r.Context().Done():
+		}
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, bytes.Count(body, []byte("\n")))
+}
+
+func (f *collector) sizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.batches...)
+}
+
+func TestHTTPBatch(t *testing.T) {
+	f := &collector{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	// 发送间隔足够长，仅按条数与Sync发送
+	c := newSinkLogger("app", SinkOption{Type: SINK_HTTP, Address: srv.URL, BatchSize: 3, FlushInterval: 60000})
+	defer c.Close()
+	for i := 0; i < 7; i++ {
+		c.Info(testCtx, Message{Text: "line"})
+	}
+	if err := c.Kernel.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("batches = %v, want [3 3 1]", got)
+	}
+	c.Info(testCtx, Message{Text: "last"})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.sizes(); len(got) != 4 || got[3] != 1 {
+		t.Errorf("batches = %v, want remaining line sent on Close", got)
+	}
+}
+
+// 阻塞的输出端不影响其他输出端，关闭时不超过等待时间
+func TestStalledSink(t *testing.T) {
+	f := &collector{stall: make(chan struct{})}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	defer close(f.stall)
+	path := filepath.Join(t.TempDir(), "app.log")
+	c := newSinkLogger("app",
+		SinkOption{Type: SINK_HTTP, Address: srv.URL, BatchSize: 1, QueueSize: 4},
+		SinkOption{Type: SINK_FILE, File: FileOption{Path: path}},
+	)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		c.Info(testCtx, Message{Text: "line"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writes took %s", elapsed)
+	}
+	start = time.Now()
+	if err := c.Close(); err == nil {
+		t.Error("Close should report the stalled sink")
+	}
+	if elapsed := time.Since(start); elapsed > 3*DEFAULT_SYNC_TIMEOUT {
+		t.Errorf("Close took %s", elapsed)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(content, []byte("\n")); n != 100 {
+		t.Errorf("file lines = %d, want 100", n)
+	}
+}
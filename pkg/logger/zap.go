@@ -4,8 +4,13 @@ package logger
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/aivencs/box/pkg/validate"
 	"go.uber.org/zap"
@@ -54,10 +59,12 @@ type Logger interface {
 }
 
 type ZapLogger struct {
+	*levels
 	Kernel      *zap.Logger
 	Env         string `json:"env" label:"环境"`
 	Application string `json:"application"  label:"应用名称"`
 	Label       string `json:"label" label:"别称"`
+	closers     []io.Closer
 }
 
 // 日志信息主体
@@ -86,10 +93,14 @@ type Attr struct {
 
 // 初始化时所用参数
 type Option struct {
-	Application string         `json:"application" label:"应用名称" desc:"必须与远端配置名称相同" validate:"required"`
-	Env         string         `json:"env" label:"环境" desc:"推荐不同环境不同配置" validate:"required"`
-	Label       string         `json:"label" label:"别称" desc:"用于后续日志细分" validate:"required"`
-	Encode      EncoderSupport `json:"encoder" label:"输出格式" desc:""`
+	Application   string         `json:"application" label:"应用名称" desc:"必须与远端配置名称相同" validate:"required"`
+	Env           string         `json:"env" label:"环境" desc:"推荐不同环境不同配置" validate:"required"`
+	Label         string         `json:"label" label:"别称" desc:"用于后续日志细分" validate:"required"`
+	Encode        EncoderSupport `json:"encoder" label:"输出格式" desc:""`
+	File          FileOption     `json:"file" label:"日志文件" desc:"填写路径时输出到文件"`
+	DisableStdout bool           `json:"disable_stdout" label:"是否关闭标准输出" desc:"默认开启"`
+	Level         LevelSupport   `json:"level" label:"日志级别" desc:"可在运行时调整" default:"info" validate:"omitempty,oneof=debug info warn error fatal"`
+	Sinks         []SinkOption   `json:"sinks" label:"输出端" desc:"填写时按输出端输出，忽略file与disable_stdout" validate:"dive"`
 }
 
 func init() {
@@ -139,27 +150,85 @@ func NewZapLogger(ctx context.Context, option Option) Logger {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
-	// 构建输出方式
-	zapWriterSync := zapcore.AddSync(os.Stdout)
 	// 构建输出格式
 	encoder := applyEncoder(option.Encode, enc)
-	// 应用参数
-	zapCore := zapcore.NewCore(
-		encoder,
-		zapWriterSync,
-		zapcore.InfoLevel,
-	)
+	// 构建日志级别，参数已校验
+	level, _ := parseLevel(option.Level)
+	lv := newLevels(level)
+	// 构建输出方式
+	cores := []zapcore.Core{}
+	closers := []io.Closer{}
+	switch {
+	case len(option.Sinks) > 0:
+		cores, closers = sinkCores(enc, option.Sinks, lv.floor, option.Application)
+		if len(cores) == 0 {
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), lv.floor))
+		}
+	case !option.DisableStdout:
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), lv.floor))
+	}
+	if len(option.Sinks) == 0 && utf8.RuneCountInString(option.File.Path) > 0 {
+		files, writers, err := fileCores(encoder, option.File, lv.floor)
+		if err != nil {
+			// 文件不可用时仍输出到标准输出，避免日志丢失
+			fmt.Fprintf(os.Stderr, "日志文件不可用: %s\n", err)
+			if option.DisableStdout {
+				cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), lv.floor))
+			}
+		}
+		cores = append(cores, files...)
+		closers = append(closers, writers...)
+	}
 	// 根据参数创建日志对象
-	logger := zap.New(zapCore, zap.AddCaller(), zap.AddCallerSkip(3))
+	logger := zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddCallerSkip(3))
 	defer logger.Sync()
 	return &ZapLogger{
+		levels:      lv,
 		Kernel:      logger,
 		Env:         option.Env,
 		Application: option.Application,
+		closers:     closers,
 		Label:       option.Label,
 	}
 }
 
+// 构建文件输出，按级别分文件时每个级别一个文件，error及以上写入error文件
+// 低于当前级别的文件在首次写入时创建，避免留下空文件
+func fileCores(encoder Encoder, option FileOption, enabler zapcore.LevelEnabler) ([]zapcore.Core, []io.Closer, error) {
+	if !option.SplitLevel {
+		writer, err := NewRotateWriter(option)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []zapcore.Core{zapcore.NewCore(encoder, writer, enabler)}, []io.Closer{writer}, nil
+	}
+	ext := filepath.Ext(option.Path)
+	base := strings.TrimSuffix(option.Path, ext)
+	cores := []zapcore.Core{}
+	closers := []io.Closer{}
+	for _, level := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel} {
+		level := level
+		sub := option
+		sub.Path = fmt.Sprintf("%s.%s%s", base, level.String(), ext)
+		writer, err := newRotateWriter(sub, !enabler.Enabled(level))
+		if err != nil {
+			return cores, closers, err
+		}
+		only := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			if !enabler.Enabled(l) {
+				return false
+			}
+			if level == zapcore.ErrorLevel {
+				return l >= zapcore.ErrorLevel
+			}
+			return l == level
+		})
+		cores = append(cores, zapcore.NewCore(encoder.Clone(), writer, only))
+		closers = append(closers, writer)
+	}
+	return cores, closers, nil
+}
+
 // 应用输出格式
 func applyEncoder(types EncoderSupport, enc EncoderConfig) Encoder {
 	switch types {
@@ -199,22 +268,31 @@ func (c *ZapLogger) build(ctx context.Context, message Message) []zapcore.Field
 }
 
 func (c *ZapLogger) write(ctx context.Context, level string, message Message) {
+	l, err := parseLevel(LevelSupport(level))
+	if err != nil {
+		l = zapcore.InfoLevel
+	}
+	label := message.Label
+	if label == "" {
+		label = c.Label
+	}
+	// 按别称覆盖的级别逐条判断
+	if !c.enabled(label, l) {
+		return
+	}
 	content := c.build(ctx, message)
-	switch level {
-	case string(DEBUG):
-		c.Kernel.Info(message.Text, content...)
-	case string(INFO):
-		c.Kernel.Info(message.Text, content...)
-	case string(WARN):
+	switch l {
+	case zapcore.DebugLevel:
+		c.Kernel.Debug(message.Text, content...)
+	case zapcore.WarnLevel:
 		c.Kernel.Warn(message.Text, content...)
-	case string(ERROR):
+	case zapcore.ErrorLevel:
 		c.Kernel.Error(message.Text, content...)
-	case string(FATAL):
+	case zapcore.FatalLevel:
 		c.Kernel.Fatal(message.Text, content...)
 	default:
 		c.Kernel.Info(message.Text, content...)
 	}
-
 }
 
 func (c *ZapLogger) Debug(ctx context.Context, message Message) {
@@ -233,6 +311,23 @@ func (c *ZapLogger) Fatal(ctx context.Context, message Message) {
 	c.write(ctx, "fatal", message)
 }
 
+// 写出缓冲的日志并关闭文件等输出端，之后的日志不再写入这些输出端
+func (c *ZapLogger) Close() error {
+	c.Kernel.Sync()
+	var ers error
+	for _, closer := range c.closers {
+		if err := closer.Close(); err != nil && ers == nil {
+			ers = err
+		}
+	}
+	return ers
+}
+
+// 是否已初始化
+func Initialized() bool {
+	return logger != nil
+}
+
 func Debug(ctx context.Context, message Message) {
 	logger.Debug(ctx, message)
 }
@@ -248,3 +343,11 @@ func Error(ctx context.Context, message Message) {
 func Fatal(ctx context.Context, message Message) {
 	logger.Fatal(ctx, message)
 }
+
+// 关闭日志的输出端，通常在程序退出前调用
+func Close() error {
+	if c, ok := logger.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
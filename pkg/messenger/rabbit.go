@@ -48,7 +48,7 @@ type Option struct {
 	Password  string `json:"password" label:"密码"`
 	Topic     Topic  `json:"topic" label:"topic" validate:"required"`
 	Heartbeat int    `json:"heartbeat" label:"心跳间隔"`
-	Qos       int    `json:"qos" label:"限流数"`
+	Qos       int    `json:"qos" label:"限流数" default:"1"`
 }
 
 type SentPayload struct {
@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// 问题说明
+type Problem struct {
+	Key     string              `json:"key" label:"键名"`
+	Level   logger.LevelSupport `json:"level" label:"级别" desc:"error为必须修改，warn为建议修改"`
+	Message string              `json:"message" label:"说明"`
+}
+
+func (c Problem) String() string {
+	return fmt.Sprintf("[%s] %s: %s", c.Level, c.Key, c.Message)
+}
+
+// 按结构体检查配置文档
+// format为yaml或json；含enable字段且未启用的部分不检查必填项
+func Lint(doc []byte, format string, v interface{}) ([]Problem, error) {
+	vip := viper.New()
+	vip.SetConfigType(format)
+	if err := vip.ReadConfig(bytes.NewReader(doc)); err != nil {
+		return nil, logger.NewError(logger.EDERROR, "配置解析失败", err)
+	}
+	problems := []Problem{}
+	lint(&problems, Parse(v), vip.AllSettings())
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Key < problems[j].Key
+	})
+	return problems, nil
+}
+
+func lint(problems *[]Problem, parent *Field, settings map[string]interface{}) {
+	known := map[string]*Field{}
+	for _, field := range parent.Fields {
+		known[strings.ToLower(field.Name)] = field
+	}
+	enabled := true
+	if _, ok := known["enable"]; ok {
+		enabled = cast.ToBool(settings["enable"])
+	}
+	for key, val := range settings {
+		field, ok := known[key]
+		if !ok {
+			*problems = append(*problems, Problem{Key: join(parent.Path, key), Level: logger.WARN, Message: "未知的配置项"})
+			continue
+		}
+		check(problems, field, val)
+	}
+	if !enabled {
+		return
+	}
+	for name, field := range known {
+		if _, ok := settings[name]; !ok && field.Required {
+			*problems = append(*problems, Problem{Key: field.Path, Level: logger.ERROR, Message: "缺少必填项"})
+		}
+	}
+}
+
+// 检查单个值
+func check(problems *[]Problem, field *Field, val interface{}) {
+	add := func(level logger.LevelSupport, format string, args ...interface{}) {
+		*problems = append(*problems, Problem{Key: field.Path, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+	if val == nil {
+		return
+	}
+	var size *float64
+	switch field.Kind {
+	case OBJECT:
+		sub, ok := val.(map[string]interface{})
+		if !ok {
+			add(logger.ERROR, "应为对象")
+			return
+		}
+		if field.Items == nil {
+			lint(problems, field, sub)
+			return
+		}
+		for key, item := range sub {
+			elem := *field.Items
+			elem.Path = join(field.Path, key)
+			check(problems, &elem, item)
+		}
+		return
+	case ARRAY:
+		list, ok := val.([]interface{})
+		if !ok {
+			add(logger.ERROR, "应为数组")
+			return
+		}
+		n := float64(len(list))
+		size = &n
+		for i, item := range list {
+			elem := *field.Items
+			elem.Path = fmt.Sprintf("%s[%d]", field.Path, i)
+			check(problems, &elem, item)
+		}
+	case STRING:
+		if field.Format == FORMAT_DURATION {
+			if _, err := cast.ToDurationE(val); err != nil {
+				add(logger.ERROR, "应为时长，例如120s")
+			}
+			return
+		}
+		text, ok := val.(string)
+		if !ok {
+			add(logger.WARN, "应为字符串")
+			text = cast.ToString(val)
+		}
+		n := float64(utf8.RuneCountInString(text))
+		size = &n
+		if len(field.Enum) > 0 && !contains(field.Enum, text) {
+			add(logger.ERROR, "应为[%s]其中之一", strings.Join(field.Enum, " "))
+		}
+	case INTEGER, NUMBER:
+		n, err := cast.ToFloat64E(val)
+		if err != nil {
+			add(logger.ERROR, "应为数字")
+			return
+		}
+		if _, ok := val.(string); ok {
+			add(logger.WARN, "应为数字而非字符串")
+		}
+		if field.Kind == INTEGER && n != float64(int64(n)) {
+			add(logger.ERROR, "应为整数")
+		}
+		size = &n
+	case BOOLEAN:
+		if _, ok := val.(bool); !ok {
+			if _, err := cast.ToBoolE(val); err != nil {
+				add(logger.ERROR, "应为布尔值")
+				return
+			}
+			add(logger.WARN, "应为布尔值而非字符串")
+		}
+	}
+	if size == nil {
+		return
+	}
+	if field.Minimum != nil && *size < *field.Minimum {
+		add(logger.ERROR, "不应小于%v", *field.Minimum)
+	}
+	if field.Maximum != nil && *size > *field.Maximum {
+		add(logger.ERROR, "不应大于%v", *field.Maximum)
+	}
+}
+
+func contains(list []string, val string) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
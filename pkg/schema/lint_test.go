@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type lintServer struct {
+	Enable  bool          `json:"enable" label:"是否启用"`
+	Host    string        `json:"host" label:"服务地址" validate:"required"`
+	Port    int           `json:"port" label:"端口号" validate:"required,min=3000,max=10000"`
+	Mode    string        `json:"mode" label:"模式" desc:"默认为release" validate:"omitempty,oneof=debug release"`
+	Timeout time.Duration `json:"timeout" label:"超时时间"`
+}
+
+type lintConf struct {
+	Name   string     `json:"name" label:"名称" validate:"required"`
+	Server lintServer `json:"server" label:"服务"`
+	Tags   []string   `json:"tags" label:"标签" validate:"max=2"`
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc:  "name: a\nserver: {enable: true, host: h, port: 3000, mode: debug, timeout: 90s}\ntags: [a, b]",
+		},
+		{
+			name: "unknown keys",
+			doc:  "name: a\nextra: 1\nserver: {other: x}",
+			want: []string{"[warn] extra: 未知的配置项", "[warn] server.other: 未知的配置项"},
+		},
+		{
+			name: "missing required",
+			doc:  "server: {enable: false}",
+			want: []string{"[error] name: 缺少必填项"},
+		},
+		{
+			name: "missing required in enabled section",
+			doc:  "name: a\nserver: {enable: true}",
+			want: []string{"[error] server.host: 缺少必填项", "[error] server.port: 缺少必填项"},
+		},
+		{
+			name: "required skipped when disabled",
+			doc:  "name: a\nserver: {enable: false, mode: debug}",
+		},
+		{
+			name: "required skipped without enable",
+			doc:  "name: a\nserver: {mode: debug}",
+		},
+		{
+			name: "enum",
+			doc:  "name: a\nserver: {mode: test}",
+			want: []string{"[error] server.mode: 应为[debug release]其中之一"},
+		},
+		{
+			name: "below minimum",
+			doc:  "name: a\nserver: {port: 80}",
+			want: []string{"[error] server.port: 不应小于3000"},
+		},
+		{
+			name: "above maximum",
+			doc:  "name: a\nserver: {port: 20000}\ntags: [a, b, c]",
+			want: []string{"[error] server.port: 不应大于10000", "[error] tags: 不应大于2"},
+		},
+		{
+			name: "number as string",
+			doc:  "name: a\nserver: {port: \"3000\"}",
+			want: []string{"[warn] server.port: 应为数字而非字符串"},
+		},
+		{
+			name: "not an integer",
+			doc:  "name: a\nserver: {port: 3000.5}",
+			want: []string{"[error] server.port: 应为整数"},
+		},
+		{
+			name: "duration",
+			doc:  "name: a\nserver: {timeout: 1d}",
+			want: []string{"[error] server.timeout: 应为时长，例如120s"},
+		},
+		{
+			name: "duration as nanoseconds",
+			doc:  "name: a\nserver: {timeout: 5000000000}",
+		},
+		{
+			name: "not an object",
+			doc:  "name: a\nserver: 1",
+			want: []string{"[error] server: 应为对象"},
+		},
+	}
+	for _, tt := range tests {
+		problems, err := Lint([]byte(tt.doc), "yaml", lintConf{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, problem := range problems {
+			got = append(got, problem.String())
+		}
+		if len(tt.want) == 0 {
+			tt.want = []string{}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Lint = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLintInvalidDocument(t *testing.T) {
+	if _, err := Lint([]byte("name: ["), "yaml", lintConf{}); err == nil {
+		t.Error("Lint with invalid document succeeded")
+	}
+}
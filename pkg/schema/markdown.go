@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 生成Markdown格式的配置说明
+// 每行一个配置项，嵌套字段以点号连接
+func Markdown(v interface{}, title string) string {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "## %s\n\n", title)
+	}
+	b.WriteString("| 键名 | 类型 | 必填 | 默认值 | 名称 | 说明 | 校验 |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	rows(&b, Parse(v))
+	return b.String()
+}
+
+func rows(b *strings.Builder, parent *Field) {
+	for _, field := range parent.Fields {
+		required := ""
+		if field.Required {
+			required = "是"
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s | %s | %s |\n",
+			field.Path, kindText(field), required, defaultText(field.Default),
+			cell(field.Label), cell(field.Desc), cell(field.Rule))
+		if field.Kind == OBJECT && field.Items == nil {
+			rows(b, field)
+		}
+		if field.Items != nil && len(field.Items.Fields) > 0 {
+			rows(b, field.Items)
+		}
+	}
+}
+
+func kindText(field *Field) string {
+	text := string(field.Kind)
+	if field.Kind == ANY {
+		text = "any"
+	}
+	if field.Items != nil {
+		text = fmt.Sprintf("%s<%s>", text, kindText(field.Items))
+	}
+	if field.Format != "" {
+		text = fmt.Sprintf("%s(%s)", text, field.Format)
+	}
+	return text
+}
+
+func defaultText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("`%v`", v)
+}
+
+// 转义表格中的竖线与换行
+func cell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
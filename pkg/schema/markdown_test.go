@@ -0,0 +1,28 @@
+package schema
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "更新golden文件")
+
+func TestMarkdownGolden(t *testing.T) {
+	got := Markdown(lintConf{Server: lintServer{Timeout: 30 * time.Second}}, "示例")
+	path := filepath.Join("testdata", "markdown.golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("Markdown mismatch, rerun with -update if intended\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
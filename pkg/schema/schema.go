@@ -0,0 +1,283 @@
+// 根据结构体标签生成配置说明与校验配置文档
+// 读取json、label、desc、validate与default标签
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 使用枚举限定字段类型，与JSON Schema一致
+type KindSupport string
+
+const (
+	OBJECT  KindSupport = "object"
+	ARRAY   KindSupport = "array"
+	STRING  KindSupport = "string"
+	INTEGER KindSupport = "integer"
+	NUMBER  KindSupport = "number"
+	BOOLEAN KindSupport = "boolean"
+	ANY     KindSupport = "" // 不限类型
+	// 定义默认值
+	DEFAULT_DIALECT = "https://json-schema.org/draft/2020-12/schema"
+	// JSON Schema的duration为ISO 8601格式，Go时长使用自定义格式
+	FORMAT_DURATION  = "go-duration"
+	PATTERN_DURATION = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+)
+
+// 字段说明
+type Field struct {
+	Name     string      `json:"name" label:"键名"`
+	Path     string      `json:"path" label:"完整键名" desc:"以点号连接"`
+	Kind     KindSupport `json:"kind" label:"类型"`
+	Format   string      `json:"format" label:"格式" desc:"例如go-duration、url"`
+	Label    string      `json:"label" label:"名称"`
+	Desc     string      `json:"desc" label:"说明"`
+	Rule     string      `json:"rule" label:"校验规则"`
+	Required bool        `json:"required" label:"是否必填"`
+	Default  interface{} `json:"default" label:"默认值"`
+	Enum     []string    `json:"enum" label:"可选值"`
+	Minimum  *float64    `json:"minimum" label:"最小值" desc:"字符串与数组为长度"`
+	Maximum  *float64    `json:"maximum" label:"最大值" desc:"字符串与数组为长度"`
+	Fields   []*Field    `json:"fields" label:"子字段"`
+	Items    *Field      `json:"items" label:"数组元素"`
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 解析结构体
+// v可为结构体或其指针，非零字段值视为默认值，default标签优先
+func Parse(v interface{}) *Field {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value = reflect.New(value.Type().Elem())
+		}
+		value = value.Elem()
+	}
+	root := &Field{}
+	parse(root, "", value.Type(), value)
+	return root
+}
+
+func parse(field *Field, path string, kind reflect.Type, value reflect.Value) {
+	for kind.Kind() == reflect.Ptr {
+		kind = kind.Elem()
+		if value.IsValid() && !value.IsNil() {
+			value = value.Elem()
+		} else {
+			value = reflect.Value{}
+		}
+	}
+	switch {
+	case kind == durationType:
+		field.Kind, field.Format = STRING, FORMAT_DURATION
+		if value.IsValid() && !value.IsZero() {
+			field.Default = value.Interface().(time.Duration).String()
+		}
+		return
+	case kind.Kind() == reflect.Struct:
+		field.Kind = OBJECT
+		fields(field, path, kind, value)
+		return
+	case kind.Kind() == reflect.Map:
+		field.Kind = OBJECT
+		field.Items = &Field{}
+		parse(field.Items, path+".*", kind.Elem(), reflect.Value{})
+		return
+	case kind.Kind() == reflect.Slice || kind.Kind() == reflect.Array:
+		field.Kind = ARRAY
+		field.Items = &Field{}
+		parse(field.Items, path+"[]", kind.Elem(), reflect.Value{})
+	case kind.Kind() == reflect.String:
+		field.Kind = STRING
+	case kind.Kind() == reflect.Bool:
+		field.Kind = BOOLEAN
+	case kind.Kind() >= reflect.Int && kind.Kind() <= reflect.Uint64:
+		field.Kind = INTEGER
+	case kind.Kind() == reflect.Float32 || kind.Kind() == reflect.Float64:
+		field.Kind = NUMBER
+	default:
+		field.Kind = ANY
+	}
+	if value.IsValid() && !value.IsZero() && field.Kind != ARRAY {
+		field.Default = value.Interface()
+	}
+}
+
+// 解析结构体的各字段，匿名字段或标签含squash时展开到上一级
+func fields(parent *Field, path string, kind reflect.Type, value reflect.Value) {
+	for i := 0; i < kind.NumField(); i++ {
+		sf := kind.Field(i)
+		name, opts := tagName(sf.Tag.Get("json"))
+		if name == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		var sub reflect.Value
+		if value.IsValid() {
+			sub = value.Field(i)
+		}
+		if sf.Anonymous && (name == "" || strings.Contains(opts, "squash")) {
+			parse(parent, path, sf.Type, sub)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		field := &Field{
+			Name:  name,
+			Path:  join(path, name),
+			Label: sf.Tag.Get("label"),
+			Desc:  sf.Tag.Get("desc"),
+			Rule:  sf.Tag.Get("validate"),
+		}
+		parse(field, field.Path, sf.Type, sub)
+		if raw, ok := sf.Tag.Lookup("default"); ok {
+			field.Default = convert(field.Kind, raw)
+		}
+		rules(field)
+		parent.Fields = append(parent.Fields, field)
+	}
+}
+
+// 解析validate标签
+func rules(field *Field) {
+	for _, rule := range strings.Split(field.Rule, ",") {
+		pair := strings.SplitN(rule, "=", 2)
+		param := ""
+		if len(pair) == 2 {
+			param = pair[1]
+		}
+		switch pair[0] {
+		case "required":
+			field.Required = true
+		case "min", "gte":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				field.Minimum = &n
+			}
+		case "max", "lte":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				field.Maximum = &n
+			}
+		case "len":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				field.Minimum, field.Maximum = &n, &n
+			}
+		case "oneof":
+			field.Enum = strings.Fields(param)
+		case "url", "email", "ip":
+			field.Format = pair[0]
+		}
+	}
+}
+
+func tagName(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// 按字段类型转换default标签
+func convert(kind KindSupport, raw string) interface{} {
+	switch kind {
+	case INTEGER:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	case NUMBER:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case BOOLEAN:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// 生成JSON Schema
+func JSONSchema(v interface{}, title string) ([]byte, error) {
+	doc := document(Parse(v))
+	doc["$schema"] = DEFAULT_DIALECT
+	if title != "" {
+		doc["title"] = title
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func document(field *Field) map[string]interface{} {
+	doc := map[string]interface{}{}
+	if field.Kind != ANY {
+		doc["type"] = string(field.Kind)
+	}
+	if field.Format == FORMAT_DURATION {
+		// 时长可写作字符串或纳秒数，pattern仅约束字符串
+		doc["type"] = []string{string(STRING), string(INTEGER)}
+		doc["pattern"] = PATTERN_DURATION
+	}
+	title := strings.TrimSpace(field.Label)
+	if title != "" {
+		doc["title"] = title
+	}
+	if field.Desc != "" {
+		doc["description"] = field.Desc
+	}
+	if field.Default != nil {
+		doc["default"] = field.Default
+	}
+	if field.Format != "" {
+		doc["format"] = field.Format
+	}
+	if len(field.Enum) > 0 {
+		doc["enum"] = field.Enum
+	}
+	min, max := "minimum", "maximum"
+	switch field.Kind {
+	case STRING:
+		min, max = "minLength", "maxLength"
+	case ARRAY:
+		min, max = "minItems", "maxItems"
+	}
+	if field.Minimum != nil {
+		doc[min] = *field.Minimum
+	}
+	if field.Maximum != nil {
+		doc[max] = *field.Maximum
+	}
+	if field.Items != nil {
+		key := "items"
+		if field.Kind == OBJECT {
+			key = "additionalProperties"
+		}
+		doc[key] = document(field.Items)
+	}
+	if field.Kind == OBJECT && field.Items == nil {
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, sub := range field.Fields {
+			properties[sub.Name] = document(sub)
+			if sub.Required {
+				required = append(required, sub.Name)
+			}
+		}
+		doc["properties"] = properties
+		if len(required) > 0 {
+			sort.Strings(required)
+			doc["required"] = required
+		}
+	}
+	return doc
+}
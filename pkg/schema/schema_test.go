@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// 时长使用自定义格式，字符串按Go时长校验
+func TestDurationSchema(t *testing.T) {
+	type option struct {
+		Timeout time.Duration `json:"timeout" label:"超时时间"`
+	}
+	content, err := JSONSchema(option{Timeout: 2 * time.Minute}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Properties map[string]struct {
+			Format  string `json:"format"`
+			Pattern string `json:"pattern"`
+			Default string `json:"default"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	field := doc.Properties["timeout"]
+	if field.Format != FORMAT_DURATION || field.Default != "2m0s" {
+		t.Fatalf("timeout = %+v", field)
+	}
+	pattern := regexp.MustCompile(field.Pattern)
+	for _, text := range []string{"0", "120s", "1h30m", "-1.5h", "300ms", "2us"} {
+		if _, err := time.ParseDuration(text); err != nil || !pattern.MatchString(text) {
+			t.Errorf("%q should match", text)
+		}
+	}
+	for _, text := range []string{"", "PT2M", "120", "1d", "s"} {
+		if _, err := time.ParseDuration(text); err == nil || pattern.MatchString(text) {
+			t.Errorf("%q should not match", text)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aivencs/box/pkg/config"
+	"github.com/aivencs/box/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DEFAULT_AUDIT_PATH = "/admin/config/audits" // 配置变更记录的默认路径
+	DEFAULT_LEVEL_PATH = "/admin/logger/level"  // 日志级别的默认路径
+)
+
+// 查询配置变更记录
+// 支持limit参数，仅返回最近的若干条
+func ConfigAuditHandler(c echo.Context) error {
+	audits := config.Audits()
+	if limit, err := strconv.Atoi(c.QueryParam("limit")); err == nil && limit >= 0 && limit < len(audits) {
+		audits = audits[len(audits)-limit:]
+	}
+	trace, _ := c.Get("trace").(string)
+	base := logger.GetBaseCode(logger.SUCCESS)
+	res := ServerResponse{
+		Code:    logger.SUCCESS,
+		Trace:   trace,
+		Message: base.Label(),
+		Result: map[string]interface{}{
+			"revision": config.SourceRevision(),
+			"audits":   audits,
+		},
+	}
+	return c.JSONPretty(http.StatusOK, res, "")
+}
+
+// 注册管理接口
+// 管理接口可调整日志级别，auth用于限制访问，不可为空
+func AddAdminRouter(auth echo.MiddlewareFunc, m ...echo.MiddlewareFunc) error {
+	if auth == nil {
+		return logger.NewError(logger.PVERROR, "管理接口必须指定访问控制中间件", nil)
+	}
+	m = append([]echo.MiddlewareFunc{auth}, m...)
+	AddRouter(RouterPayload{Method: GET, Path: DEFAULT_AUDIT_PATH, Label: "配置变更记录"}, ConfigAuditHandler, m...)
+	level := echo.WrapHandler(logger.LevelHandler())
+	AddRouter(RouterPayload{Method: GET, Path: DEFAULT_LEVEL_PATH, Label: "查看日志级别"}, level, m...)
+	AddRouter(RouterPayload{Method: PUT, Path: DEFAULT_LEVEL_PATH, Label: "调整日志级别"}, level, m...)
+	return nil
+}
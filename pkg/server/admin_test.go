@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 未指定访问控制中间件时拒绝注册管理接口
+func TestAdminRequiresAuth(t *testing.T) {
+	if err := AddAdminRouter(nil); err == nil {
+		t.Error("AddAdminRouter(nil) should fail")
+	}
+}
+
+// 管理接口均经过访问控制中间件
+func TestAdminAuth(t *testing.T) {
+	saved := server
+	defer func() { server = saved }()
+	c, err := NewEchoServer(context.Background(), Option{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = c
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("X-ADMIN-TOKEN") != "secret" {
+				return c.NoContent(http.StatusUnauthorized)
+			}
+			return next(c)
+		}
+	}
+	if err := AddAdminRouter(auth); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: DEFAULT_AUDIT_PATH},
+		{method: http.MethodGet, path: DEFAULT_LEVEL_PATH},
+		{method: http.MethodPut, path: DEFAULT_LEVEL_PATH, body: `{"level":"debug"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		c.(*EchoServer).Kernel.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: status = %d, want 401", tt.method, tt.path, rec.Code)
+		}
+	}
+}
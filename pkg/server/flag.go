@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/aivencs/box/pkg/flag"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DEFAULT_FLAG_HEADER = "X-USER-ID" // 功能开关默认使用的用户标识请求头
+)
+
+// 获取用于功能开关判定的用户标识
+type FlagKeyFunc func(c echo.Context) string
+
+// 功能开关中间件
+// 判定所有开关并以flags存入echo上下文，未指定用户标识时依次使用请求头X-USER-ID与客户端IP
+// 功能开关未初始化时不做处理
+func FlagMiddleware(key FlagKeyFunc) echo.MiddlewareFunc {
+	if key == nil {
+		key = func(c echo.Context) string {
+			if id := c.Request().Header.Get(DEFAULT_FLAG_HEADER); len(id) > 0 {
+				return id
+			}
+			return c.RealIP()
+		}
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !flag.Initialized() {
+				return next(c)
+			}
+			c.Set("flags", flag.All(c.Request().Context(), key(c)))
+			return next(c)
+		}
+	}
+}
+
+// 读取中间件判定的开关结果
+func GetFlags(c echo.Context) map[string]flag.Result {
+	flags, _ := c.Get("flags").(map[string]flag.Result)
+	return flags
+}
+
+// 开关是否开启，未经过中间件或未定义时为false
+func FlagEnabled(c echo.Context, name string) bool {
+	return GetFlags(c)[strings.ToLower(name)].Enabled
+}
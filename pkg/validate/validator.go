@@ -23,6 +23,7 @@ var once sync.Once
 // 定义接口
 type Validate interface {
 	Work(ctx context.Context, payload interface{}) (string, error)
+	Messages(ctx context.Context, payload interface{}) ([]string, error)
 }
 
 // 定义选项参数
@@ -67,64 +68,85 @@ func NewValidator(ctx context.Context, option Option) Validate {
 }
 
 func (c *ValidatorValidate) Work(ctx context.Context, payload interface{}) (string, error) {
-	message := ""
+	messages, err := c.Messages(ctx, payload)
+	if err != nil {
+		return messages[len(messages)-1], err
+	}
+	return "", nil
+}
+
+// 返回所有未通过校验的字段说明
+func (c *ValidatorValidate) Messages(ctx context.Context, payload interface{}) ([]string, error) {
 	err := c.Kernel.Struct(payload)
 	if err == nil {
-		return "", err
+		return nil, err
 	}
-	for _, err := range err.(validator.ValidationErrors) {
-		switch err.Tag() {
-		case "required":
-			message = fmt.Sprintf("%s为必填项", err.Field())
-		case "min":
-			message = fmt.Sprintf("%s的长度不应小于%v", err.Field(), err.Param())
-		case "max":
-			message = fmt.Sprintf("%s的长度不应超过%v", err.Field(), err.Param())
-		case "ne":
-			message = fmt.Sprintf("%s的值不应为%v", err.Field(), err.Value())
-		case "len":
-			message = fmt.Sprintf("%s的长度必须为%v", err.Field(), err.Param())
-		case "eq":
-			message = fmt.Sprintf("%s的值必须为%v", err.Field(), err.Param())
-		case "oneof":
-			message = fmt.Sprintf("%s的值必须在[%v]其中", err.Field(), err.Param())
-		case "gt":
-			message = fmt.Sprintf("%s的值必须大于%v", err.Field(), err.Param())
-		case "gte":
-			message = fmt.Sprintf("%s的值必须大于或等于%v", err.Field(), err.Param())
-		case "lt":
-			message = fmt.Sprintf("%s的值必须小于%v", err.Field(), err.Param())
-		case "lte":
-			message = fmt.Sprintf("%s的值必须小于或等于%v", err.Field(), err.Param())
-		case "eqfield":
-			message = fmt.Sprintf("%s的值必须与%v的值相等", err.Field(), err.Param())
-		case "numeric":
-			message = fmt.Sprintf("%s的值必须为数字", err.Field())
-		case "email":
-			message = fmt.Sprintf("%s的值必须符合邮箱格式", err.Field())
-		case "url":
-			message = fmt.Sprintf("%s的值必须符合网址格式", err.Field())
-		case "ip":
-			message = fmt.Sprintf("%s的内容必须符合IP格式", err.Field())
-		case "contains":
-			message = fmt.Sprintf("%s的值必须包含%v", err.Field(), err.Param())
-		case "excludes":
-			message = fmt.Sprintf("%s的值不可包含%v", err.Field(), err.Param())
-		case "containsany":
-			message = fmt.Sprintf("%s的值必须包含[%v]其中任意一个", err.Field(), err.Param())
-		case "excludesall":
-			message = fmt.Sprintf("%s的值不可包含[%v]其中任意一个", err.Field(), err.Param())
-		case "startswith":
-			message = fmt.Sprintf("%s的值必须以[%v]为开头", err.Field(), err.Param())
-		case "endswith":
-			message = fmt.Sprintf("%s的值必须以[%v]为结尾", err.Field(), err.Param())
-		default:
-			message = fmt.Sprintf("%s的值未通过校验", err.Field())
-		}
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []string{err.Error()}, err
+	}
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, translate(err))
+	}
+	return messages, err
+}
+
+func translate(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("%s为必填项", err.Field())
+	case "min":
+		return fmt.Sprintf("%s的长度不应小于%v", err.Field(), err.Param())
+	case "max":
+		return fmt.Sprintf("%s的长度不应超过%v", err.Field(), err.Param())
+	case "ne":
+		return fmt.Sprintf("%s的值不应为%v", err.Field(), err.Value())
+	case "len":
+		return fmt.Sprintf("%s的长度必须为%v", err.Field(), err.Param())
+	case "eq":
+		return fmt.Sprintf("%s的值必须为%v", err.Field(), err.Param())
+	case "oneof":
+		return fmt.Sprintf("%s的值必须在[%v]其中", err.Field(), err.Param())
+	case "gt":
+		return fmt.Sprintf("%s的值必须大于%v", err.Field(), err.Param())
+	case "gte":
+		return fmt.Sprintf("%s的值必须大于或等于%v", err.Field(), err.Param())
+	case "lt":
+		return fmt.Sprintf("%s的值必须小于%v", err.Field(), err.Param())
+	case "lte":
+		return fmt.Sprintf("%s的值必须小于或等于%v", err.Field(), err.Param())
+	case "eqfield":
+		return fmt.Sprintf("%s的值必须与%v的值相等", err.Field(), err.Param())
+	case "numeric":
+		return fmt.Sprintf("%s的值必须为数字", err.Field())
+	case "email":
+		return fmt.Sprintf("%s的值必须符合邮箱格式", err.Field())
+	case "url":
+		return fmt.Sprintf("%s的值必须符合网址格式", err.Field())
+	case "ip":
+		return fmt.Sprintf("%s的内容必须符合IP格式", err.Field())
+	case "contains":
+		return fmt.Sprintf("%s的值必须包含%v", err.Field(), err.Param())
+	case "excludes":
+		return fmt.Sprintf("%s的值不可包含%v", err.Field(), err.Param())
+	case "containsany":
+		return fmt.Sprintf("%s的值必须包含[%v]其中任意一个", err.Field(), err.Param())
+	case "excludesall":
+		return fmt.Sprintf("%s的值不可包含[%v]其中任意一个", err.Field(), err.Param())
+	case "startswith":
+		return fmt.Sprintf("%s的值必须以[%v]为开头", err.Field(), err.Param())
+	case "endswith":
+		return fmt.Sprintf("%s的值必须以[%v]为结尾", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("%s的值未通过校验", err.Field())
 	}
-	return message, err
 }
 
 func Work(ctx context.Context, payload interface{}) (string, error) {
 	return val.Work(ctx, payload)
 }
+
+func Messages(ctx context.Context, payload interface{}) ([]string, error) {
+	return val.Messages(ctx, payload)
+}